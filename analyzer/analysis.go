@@ -0,0 +1,163 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/configkit"
+)
+
+// analysis is the result of analyzing a repository.
+//
+// A repository may be analyzed under several build configurations. The results
+// of each are merged, such that each application, handler, type and diagnostic
+// appears only once.
+type analysis struct {
+	apps  []persistence.Application
+	decls []persistence.Declaration
+	defs  []persistence.TypeDef
+	diags []persistence.Diagnostic
+
+	appIndex  map[string]int
+	defIndex  map[[2]string]struct{}
+	diagIndex map[persistence.Diagnostic]struct{}
+}
+
+// addApplication adds an application that was discovered when loading packages
+// under the build configuration b.
+//
+// The application is merged with any other application that has the same
+// identity key, but is always recorded as a declaration, such that collisions
+// between applications and handlers within the repository are reported.
+func (res *analysis) addApplication(
+	app configkit.Application,
+	module string,
+	b BuildConfig,
+) {
+	if res.appIndex == nil {
+		res.appIndex = map[string]int{}
+	}
+
+	res.decls = append(res.decls, persistence.Declaration{
+		Config: app,
+		Module: module,
+	})
+
+	build := b.String()
+	key := app.Identity().Key
+
+	i, ok := res.appIndex[key]
+	if !ok {
+		i = len(res.apps)
+		res.appIndex[key] = i
+		res.apps = append(res.apps, persistence.Application{
+			Config: app,
+			Module: module,
+		})
+	}
+
+	a := &res.apps[i]
+	a.BuildConfigs = appendUnique(a.BuildConfigs, build)
+
+	for _, h := range app.Handlers() {
+		j := indexOfHandler(a.Handlers, h.Identity().Key)
+		if j == -1 {
+			j = len(a.Handlers)
+			a.Handlers = append(a.Handlers, persistence.Handler{Config: h})
+		}
+
+		x := &a.Handlers[j]
+		x.BuildConfigs = appendUnique(x.BuildConfigs, build)
+		addHandlerMessages(x, h, build)
+	}
+}
+
+// indexOfHandler returns the index of the handler with the given key, or -1
+// if there is no such handler.
+func indexOfHandler(handlers []persistence.Handler, key string) int {
+	for i, h := range handlers {
+		if h.Config.Identity().Key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// addHandlerMessages merges the messages used by h under the build
+// configuration named build into those of x.
+//
+// Message routes may be conditional on the build configuration, so a message
+// is produced or consumed by the handler if it is under any build
+// configuration.
+func addHandlerMessages(
+	x *persistence.Handler,
+	h configkit.Handler,
+	build string,
+) {
+	// Messages are kept sorted by name, such that they are stored in a
+	// consistent order.
+	for n, em := range h.MessageNames() {
+		name := n.String()
+
+		i := sort.Search(len(x.Messages), func(i int) bool {
+			return x.Messages[i].Name >= name
+		})
+
+		if i == len(x.Messages) || x.Messages[i].Name != name {
+			x.Messages = append(x.Messages, persistence.HandlerMessage{})
+			copy(x.Messages[i+1:], x.Messages[i:])
+			x.Messages[i] = persistence.HandlerMessage{
+				Name: name,
+				Kind: em.Kind,
+			}
+		}
+
+		m := &x.Messages[i]
+		m.IsProduced = m.IsProduced || em.IsProduced
+		m.IsConsumed = m.IsConsumed || em.IsConsumed
+		m.BuildConfigs = appendUnique(m.BuildConfigs, build)
+	}
+}
+
+// addTypeDef adds a type definition, unless a definition of the same type has
+// already been added.
+func (res *analysis) addTypeDef(d persistence.TypeDef) {
+	if res.defIndex == nil {
+		res.defIndex = map[[2]string]struct{}{}
+	}
+
+	k := [2]string{d.Package, d.Name}
+	if _, ok := res.defIndex[k]; ok {
+		return
+	}
+
+	res.defIndex[k] = struct{}{}
+	res.defs = append(res.defs, d)
+}
+
+// addDiagnostic adds a diagnostic, unless an identical diagnostic has already
+// been added.
+func (res *analysis) addDiagnostic(d persistence.Diagnostic) {
+	if res.diagIndex == nil {
+		res.diagIndex = map[persistence.Diagnostic]struct{}{}
+	}
+
+	if _, ok := res.diagIndex[d]; ok {
+		return
+	}
+
+	res.diagIndex[d] = struct{}{}
+	res.diags = append(res.diags, d)
+}
+
+// appendUnique appends v to s if it is not already present.
+func appendUnique(s []string, v string) []string {
+	for _, x := range s {
+		if x == v {
+			return s
+		}
+	}
+
+	return append(s, v)
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/dogmatiq/browser/githubx"
 	"github.com/dogmatiq/browser/persistence"
@@ -16,7 +17,6 @@ import (
 	"github.com/dogmatiq/configkit/static"
 	"github.com/dogmatiq/dodeca/logging"
 	"github.com/google/go-github/v38/github"
-	"golang.org/x/mod/modfile"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -26,10 +26,38 @@ type Analyzer struct {
 	DB        *sql.DB
 	Connector *githubx.Connector
 	Logger    logging.Logger
+
+	// Version is the version of the browser, which is recorded with each
+	// repository that is analyzed.
+	Version string
+
+	// BuildConfigs is the set of build configurations under which each
+	// repository is analyzed, unless the repository's configuration file
+	// specifies its own. If it is empty, packages are loaded using the
+	// server's default environment.
+	BuildConfigs []BuildConfig
 }
 
 // Analyze analyzes the repo with the given ID.
-func (a *Analyzer) Analyze(ctx context.Context, repoID int64) error {
+//
+// t is the reason that the analysis was requested. It is recorded, along with
+// the outcome and timing information, as an analysis run.
+func (a *Analyzer) Analyze(
+	ctx context.Context,
+	repoID int64,
+	t persistence.Trigger,
+) (err error) {
+	run := &persistence.AnalysisRun{
+		RepositoryID: repoID,
+		Trigger:      t,
+		StartedAt:    time.Now(),
+		Outcome:      persistence.AnalyzedOutcome,
+	}
+
+	defer func() {
+		a.recordRun(ctx, run, err)
+	}()
+
 	c, ok, err := a.Connector.RepositoryClient(ctx, repoID)
 	if err != nil {
 		return fmt.Errorf("unable to obtain github client for repository #%d: %w", repoID, err)
@@ -41,6 +69,7 @@ func (a *Analyzer) Analyze(ctx context.Context, repoID int64) error {
 			repoID,
 		)
 
+		run.Outcome = persistence.UnavailableOutcome
 		return nil
 	}
 
@@ -54,6 +83,7 @@ func (a *Analyzer) Analyze(ctx context.Context, repoID int64) error {
 				repoID,
 			)
 
+			run.Outcome = persistence.UnavailableOutcome
 			return nil
 		}
 
@@ -64,6 +94,8 @@ func (a *Analyzer) Analyze(ctx context.Context, repoID int64) error {
 		)
 	}
 
+	run.FullName = r.GetFullName()
+
 	// Then look it up again by name because the GetByID() operation doesn't
 	// return the complete repository information, such as whether the
 	// repository is archived or a template repository.
@@ -77,6 +109,7 @@ func (a *Analyzer) Analyze(ctx context.Context, repoID int64) error {
 				r.GetFullName(),
 			)
 
+			run.Outcome = persistence.UnavailableOutcome
 			return nil
 		}
 
@@ -87,51 +120,53 @@ func (a *Analyzer) Analyze(ctx context.Context, repoID int64) error {
 		)
 	}
 
-	if err := a.analyze(ctx, c, r); err != nil {
+	if err := a.analyze(ctx, c, r, run); err != nil {
 		return fmt.Errorf("unable to analyze %s: %w", r.GetFullName(), err)
 	}
 
 	return nil
 }
 
-func (a *Analyzer) analyze(
+// recordRun stores a record of an analysis run that ended with the given
+// error.
+//
+// Failure to record the run is logged but does not cause the analysis itself
+// to fail.
+func (a *Analyzer) recordRun(
 	ctx context.Context,
-	c *github.Client,
-	r *github.Repository,
-) error {
-	if r.GetIsTemplate() {
-		logging.Log(
-			a.Logger,
-			"[#%d %s] skipping analysis of template repository",
-			r.GetID(),
-			r.GetFullName(),
-		)
-
-		return nil
+	run *persistence.AnalysisRun,
+	err error,
+) {
+	if ctx.Err() != nil {
+		// The analysis was interrupted because the browser is shutting down,
+		// it will be attempted again when the job is next claimed.
+		return
 	}
 
-	if r.GetArchived() {
-		logging.Log(
-			a.Logger,
-			"[#%d %s] skipping analysis of archived repository",
-			r.GetID(),
-			r.GetFullName(),
-		)
+	run.EndedAt = time.Now()
 
-		return nil
+	if err != nil {
+		run.Outcome = persistence.FailedOutcome
+		run.Error = err.Error()
 	}
 
-	if r.GetFork() {
+	if err := persistence.RecordRun(ctx, a.DB, *run); err != nil {
 		logging.Log(
 			a.Logger,
-			"[#%d %s] skipping analysis of forked repository",
-			r.GetID(),
-			r.GetFullName(),
+			"[#%d %s] %s",
+			run.RepositoryID,
+			run.FullName,
+			err,
 		)
-
-		return nil
 	}
+}
 
+func (a *Analyzer) analyze(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	run *persistence.AnalysisRun,
+) error {
 	branch, _, err := c.Repositories.GetBranch(
 		ctx,
 		r.GetOwner().GetLogin(),
@@ -144,7 +179,47 @@ func (a *Analyzer) analyze(
 	}
 
 	commit := branch.GetCommit().GetSHA()
+	run.CommitHash = commit
+
+	// The configuration at the head of the default branch is loaded even if
+	// that commit has already been analyzed, as it defines the deployment
+	// environments, which may have changed independently of the branch.
+	var res analysis
+
+	cfg, err := a.loadConfig(ctx, c, r, commit, &res)
+	if err != nil {
+		return err
+	}
+
+	if err := a.analyzeDefaultBranch(ctx, c, r, commit, cfg, &res, run); err != nil {
+		return err
+	}
+
+	if err := a.analyzeVersions(ctx, c, r, run); err != nil {
+		return err
+	}
+
+	if err := a.analyzeEnvironments(ctx, c, r, cfg, run); err != nil {
+		return err
+	}
 
+	return a.checkPullRequests(ctx, c, r, run)
+}
+
+// analyzeDefaultBranch analyzes the head of the repository's default branch,
+// unless that commit has already been analyzed.
+//
+// cfg is the configuration at that commit, and res contains any diagnostics
+// produced when it was loaded.
+func (a *Analyzer) analyzeDefaultBranch(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	commit string,
+	cfg config,
+	res *analysis,
+	run *persistence.AnalysisRun,
+) error {
 	needsSync, err := persistence.RepositoryNeedsSync(
 		ctx,
 		a.DB,
@@ -165,144 +240,244 @@ func (a *Analyzer) analyze(
 			commit,
 		)
 
+		run.Outcome = persistence.UnchangedOutcome
 		return nil
 	}
 
-	ok, err := a.isGoModule(
-		ctx,
-		c,
-		r,
-		commit,
-	)
+	previous, _, err := persistence.AnalyzedCommit(ctx, a.DB, r.GetID())
 	if err != nil {
 		return err
 	}
 
-	var (
-		apps []configkit.Application
-		defs []persistence.TypeDef
-	)
+	reason := skipReason(r, cfg)
+
+	if reason != "" {
+		logging.Log(
+			a.Logger,
+			"[#%d %s] skipping analysis of %s",
+			r.GetID(),
+			r.GetFullName(),
+			reason,
+		)
 
-	if ok {
-		pkgs, dir, err := a.loadPackages(
+		run.Outcome = persistence.SkippedOutcome
+		res.addDiagnostic(persistence.Diagnostic{
+			Category: persistence.SkippedDiagnostic,
+			Message:  reason,
+		})
+	} else {
+		ok, err := a.analyzeSource(
 			ctx,
 			c,
 			r,
+			r.GetDefaultBranch()+" branch",
 			commit,
+			cfg,
+			run,
+			res,
 		)
 		if err != nil {
 			return err
 		}
 
-		apps, defs = a.analyzePackages(r, pkgs, dir)
+		if !ok {
+			run.Outcome = persistence.SkippedOutcome
+			reason = noModulesReason
+		}
 	}
 
-	return persistence.SyncRepository(
+	start := time.Now()
+
+	if err := persistence.SyncRepository(
 		ctx,
 		a.DB,
 		r,
 		commit,
-		apps,
-		defs,
-	)
+		cfg.Metadata(),
+		a.analyzerInfo(),
+		res.apps,
+		res.decls,
+		res.defs,
+		res.diags,
+	); err != nil {
+		return err
+	}
+
+	run.SyncDuration += time.Since(start)
+
+	// The default branch is also snapshotted so that the changes between each
+	// successive analysis can be recorded, and so that any two analyzed
+	// commits can be compared.
+	if err := a.saveSnapshot(ctx, c, r, commit, reason, res.apps, res.defs, run); err != nil {
+		return err
+	}
+
+	return persistence.RecordChangeSet(ctx, a.DB, r.GetID(), previous, commit)
 }
 
-// isGoModule returns true if the given repository has a valid go.mod file in
-// its root directory.
-func (a *Analyzer) isGoModule(
+// skipReason returns a human-readable explanation of why the given repository
+// is not analyzed, or an empty string if it should be analyzed.
+func skipReason(r *github.Repository, cfg config) string {
+	switch {
+	case cfg.Ignore:
+		return "ignored by " + configFile
+	case r.GetIsTemplate():
+		return "template repository"
+	case r.GetArchived():
+		return "archived repository"
+	case r.GetFork():
+		return "forked repository"
+	default:
+		return ""
+	}
+}
+
+// analyzeSource downloads the repository contents at the given commit and
+// analyzes each of the Go modules within it.
+//
+// ref is a human-readable description of the commit, used in log messages, such
+// as "main branch" or "v1.2.3 tag".
+//
+// It returns false if the repository does not contain any modules that can be
+// analyzed. If the source itself can not be analyzed, the error is an
+// analysisError.
+func (a *Analyzer) analyzeSource(
 	ctx context.Context,
 	c *github.Client,
 	r *github.Repository,
-	commit string,
+	ref, commit string,
+	cfg config,
+	run *persistence.AnalysisRun,
+	res *analysis,
 ) (bool, error) {
-	content, _, res, err := c.Repositories.GetContents(
-		ctx,
-		r.GetOwner().GetLogin(),
-		r.GetName(),
-		"go.mod",
-		&github.RepositoryContentGetOptions{
-			Ref: commit,
-		},
-	)
+	ok, err := a.hasGoModFile(ctx, c, r, commit)
 	if err != nil {
-		if res != nil && res.StatusCode == http.StatusNotFound {
-			logging.Log(
-				a.Logger,
-				"[#%d %s] skipping analysis of %s branch (%s), go.mod file not present",
-				r.GetID(),
-				r.GetFullName(),
-				r.GetDefaultBranch(),
-				commit,
-			)
-
-			return false, nil
-		}
-
 		return false, err
 	}
 
-	data, err := content.GetContent()
-	if err != nil {
-		return false, err
-	}
-
-	mod, err := modfile.ParseLax(
-		"go.mod",
-		[]byte(data),
-		nil,
-	)
-	if err != nil {
+	if !ok {
 		logging.Log(
 			a.Logger,
-			"[#%d %s] skipping analysis of %s branch (%s), go.mod file is invalid: %s",
+			"[#%d %s] skipping analysis of %s (%s), go.mod file not present",
 			r.GetID(),
 			r.GetFullName(),
-			r.GetDefaultBranch(),
+			ref,
 			commit,
-			err,
 		)
 
+		res.addDiagnostic(persistence.Diagnostic{
+			Category: persistence.SkippedDiagnostic,
+			Message:  "go.mod file not present",
+		})
+
 		return false, nil
 	}
 
-	if mod.Module.Mod.Path == "github.com/dogmatiq/dogma" {
-		logging.Log(
-			a.Logger,
-			"[#%d %s] skipping analysis of %s branch (%s), found dogma module: %s",
-			r.GetID(),
-			r.GetFullName(),
-			r.GetDefaultBranch(),
-			commit,
-			mod.Module.Mod.Path,
-		)
+	token, err := a.createToken(ctx, r)
+	if err != nil {
+		return false, err
+	}
 
+	start := time.Now()
+	dir, err := a.downloadRepository(ctx, c, r, commit)
+	run.DownloadDuration += time.Since(start)
+	if err != nil {
+		return false, err
+	}
+
+	// Remove the repository contents as soon as all of the modules have been
+	// loaded so that it doesn't spend any longer on disk than it needs to.
+	defer os.RemoveAll(dir)
+
+	mods, works, err := a.findModules(r, dir, res)
+	if err != nil {
+		return false, analysisError{err}
+	}
+
+	if len(mods) == 0 {
 		return false, nil
 	}
 
-	logging.Log(
-		a.Logger,
-		"[#%d %s] analyzing %s branch (%s), found module %s",
-		r.GetID(),
-		r.GetFullName(),
-		r.GetDefaultBranch(),
-		commit,
-		mod.Module.Mod.Path,
-	)
+	analyzed := false
+
+	for _, u := range a.planLoads(r, dir, mods, works, res) {
+		patterns := cfg.Patterns(u, dir, mods)
+		if len(patterns) == 0 {
+			logging.Log(
+				a.Logger,
+				"[#%d %s] skipping analysis of %s, no packages match the patterns in %s",
+				r.GetID(),
+				r.GetFullName(),
+				u,
+				configFile,
+			)
+
+			continue
+		}
+
+		for _, b := range cfg.Builds(a.BuildConfigs) {
+			logging.Log(
+				a.Logger,
+				"[#%d %s] analyzing %s (%s), %s, %s build configuration",
+				r.GetID(),
+				r.GetFullName(),
+				ref,
+				commit,
+				u,
+				b,
+			)
+
+			start := time.Now()
+			pkgs, err := a.loadPackages(ctx, u, patterns, b, token)
+			run.LoadDuration += time.Since(start)
+			if err != nil {
+				return false, analysisError{
+					fmt.Errorf("unable to load packages from %s (%s): %w", u, b, err),
+				}
+			}
+
+			a.analyzePackages(r, cfg, b, pkgs, dir, res)
+			analyzed = true
+		}
+	}
+
+	if !analyzed && len(cfg.Packages) != 0 {
+		res.addDiagnostic(persistence.Diagnostic{
+			Category: persistence.SkippedDiagnostic,
+			Position: "/" + configFile,
+			Message:  "no modules contain packages that match the configured package patterns",
+		})
+	}
 
-	return true, nil
+	return analyzed, nil
 }
 
-// loadPackages parses the Go source in the repository and returns the packages
-// it contains.
-func (a *Analyzer) loadPackages(
+// analysisError is an error that occurs while analyzing the source code of a
+// repository, as opposed to while communicating with GitHub or the database.
+//
+// Such errors are usually caused by the source code itself, and so are not
+// expected to be resolved by analyzing the same commit again.
+type analysisError struct {
+	Err error
+}
+
+func (e analysisError) Error() string {
+	return e.Err.Error()
+}
+
+func (e analysisError) Unwrap() error {
+	return e.Err
+}
+
+// createToken creates a GitHub installation token that can be used by the go
+// tool to fetch private dependencies.
+func (a *Analyzer) createToken(
 	ctx context.Context,
-	c *github.Client,
 	r *github.Repository,
-	commit string,
-) ([]*packages.Package, string, error) {
+) (string, error) {
 	inst, _, err := a.Connector.AppClient.Apps.FindRepositoryInstallationByID(ctx, r.GetID())
 	if err != nil {
-		return nil, "", err
+		return "", err
 	}
 
 	token, _, err := a.Connector.AppClient.Apps.CreateInstallationToken(
@@ -315,17 +490,26 @@ func (a *Analyzer) loadPackages(
 		},
 	)
 	if err != nil {
-		return nil, "", err
+		return "", err
 	}
 
-	dir, err := a.downloadRepository(ctx, c, r, commit)
-	if err != nil {
-		return nil, dir, err
-	}
+	return token.GetToken(), nil
+}
 
-	// Remove the repository contents immediately after we load the packages
-	// from it so that it doesn't spend any longer on disk than it needs to.
-	defer os.RemoveAll(dir)
+// loadPackages parses the Go source in the modules of the given load unit under
+// the build configuration b and returns the packages that match the given
+// patterns.
+func (a *Analyzer) loadPackages(
+	ctx context.Context,
+	u loadUnit,
+	patterns []string,
+	b BuildConfig,
+	token string,
+) ([]*packages.Package, error) {
+	var flags []string
+	if len(b.Tags) != 0 {
+		flags = append(flags, "-tags="+strings.Join(b.Tags, ","))
+	}
 
 	cfg := &packages.Config{
 		Context: ctx,
@@ -336,22 +520,20 @@ func (a *Analyzer) loadPackages(
 			packages.NeedTypes |
 			packages.NeedSyntax |
 			packages.NeedTypesInfo |
-			packages.NeedDeps,
-		Dir: dir,
+			packages.NeedDeps |
+			packages.NeedModule,
+		Dir:        u.Dir,
+		BuildFlags: flags,
 		Env: append(
-			os.Environ(),
+			append(os.Environ(), b.env()...),
+			"GOWORK="+u.GoWork,
 			// This environment variable is read by the `askpass` binary, which
 			// is part of this project.
-			"_DOGMA_BROWSER_GITHUB_TOKEN="+token.GetToken(),
+			"_DOGMA_BROWSER_GITHUB_TOKEN="+token,
 		),
 	}
 
-	pkgs, err := packages.Load(cfg, "./...")
-	if err != nil {
-		return nil, "", err
-	}
-
-	return pkgs, dir, nil
+	return packages.Load(cfg, patterns...)
 }
 
 // downloadRepository downloads the repository contents at the given commit and
@@ -389,17 +571,32 @@ func (a *Analyzer) downloadRepository(
 	)
 }
 
+// analyzePackages analyzes the given packages, which were loaded under the
+// build configuration b, and adds the results to res.
+//
+// Packages that are excluded by the repository's configuration are ignored.
 func (a *Analyzer) analyzePackages(
 	r *github.Repository,
+	cfg config,
+	b BuildConfig,
 	pkgs []*packages.Package,
 	dir string,
-) ([]configkit.Application, []persistence.TypeDef) {
-	var (
-		apps []configkit.Application
-		defs []persistence.TypeDef
-	)
-
+	res *analysis,
+) {
 	for _, pkg := range pkgs {
+		if cfg.IsExcluded(pkg.PkgPath) {
+			logging.Log(
+				a.Logger,
+				"[#%d %s] skipping analysis of %s, excluded by %s",
+				r.GetID(),
+				r.GetFullName(),
+				pkg.PkgPath,
+				configFile,
+			)
+
+			continue
+		}
+
 		if len(pkg.Errors) != 0 {
 			for _, err := range pkg.Errors {
 				logging.Log(
@@ -410,6 +607,18 @@ func (a *Analyzer) analyzePackages(
 					pkg.PkgPath,
 					err,
 				)
+
+				cat := persistence.LoadErrorDiagnostic
+				if err.Kind == packages.TypeError {
+					cat = persistence.TypeErrorDiagnostic
+				}
+
+				res.addDiagnostic(persistence.Diagnostic{
+					Category: cat,
+					Package:  pkg.PkgPath,
+					Position: strings.TrimPrefix(err.Pos, dir),
+					Message:  err.Msg,
+				})
 			}
 
 			continue
@@ -423,19 +632,37 @@ func (a *Analyzer) analyzePackages(
 			pkg.PkgPath,
 		)
 
-		a, d := a.analyzePackage(r, pkg, dir)
-		apps = append(apps, a...)
-		defs = append(defs, d...)
-	}
+		var mod string
+		if pkg.Module != nil {
+			mod = pkg.Module.Path
+		}
+
+		apps, defs, diags := a.analyzePackage(r, pkg, dir)
+
+		for _, app := range apps {
+			res.addApplication(app, mod, b)
+		}
 
-	return apps, defs
+		for _, d := range defs {
+			d.Module = mod
+			res.addTypeDef(d)
+		}
+
+		for _, d := range diags {
+			res.addDiagnostic(d)
+		}
+	}
 }
 
 func (a *Analyzer) analyzePackage(
 	r *github.Repository,
 	pkg *packages.Package,
 	dir string,
-) ([]configkit.Application, []persistence.TypeDef) {
+) (
+	apps []configkit.Application,
+	defs []persistence.TypeDef,
+	diags []persistence.Diagnostic,
+) {
 	defer func() {
 		if p := recover(); p != nil {
 			logging.Log(
@@ -445,10 +672,16 @@ func (a *Analyzer) analyzePackage(
 				r.GetFullName(),
 				p,
 			)
+
+			diags = append(diags, persistence.Diagnostic{
+				Category: persistence.PanicDiagnostic,
+				Package:  pkg.PkgPath,
+				Message:  fmt.Sprint(p),
+			})
 		}
 	}()
 
-	apps := static.FromPackages([]*packages.Package{pkg})
+	apps = static.FromPackages([]*packages.Package{pkg})
 
 	for _, app := range apps {
 		logging.Log(
@@ -460,8 +693,6 @@ func (a *Analyzer) analyzePackage(
 		)
 	}
 
-	var defs []persistence.TypeDef
-
 	for _, f := range pkg.Syntax {
 		for _, d := range f.Decls {
 			d, ok := d.(*ast.GenDecl)
@@ -492,10 +723,11 @@ func (a *Analyzer) analyzePackage(
 					File:    strings.TrimPrefix(pos.Filename, dir),
 					Line:    pos.Line,
 					Docs:    d.Doc.Text(),
+					Fields:  structFields(pkg, s),
 				})
 			}
 		}
 	}
 
-	return apps, defs
+	return apps, defs, nil
 }
@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BuildConfig is a configuration under which Go packages are loaded.
+//
+// The zero value uses the server's default environment without any build tags.
+type BuildConfig struct {
+	GOOS   string   `yaml:"goos"`
+	GOARCH string   `yaml:"goarch"`
+	Tags   []string `yaml:"tags"`
+}
+
+// String returns a human-readable representation of the configuration, in the
+// same format accepted by ParseBuildConfigs.
+func (c BuildConfig) String() string {
+	var s string
+
+	if c.GOOS != "" || c.GOARCH != "" {
+		s = c.GOOS + "/" + c.GOARCH
+	}
+
+	for _, t := range c.Tags {
+		s += "+" + t
+	}
+
+	if s == "" {
+		return "default"
+	}
+
+	return s
+}
+
+// env returns the environment variables that select the configuration's target
+// platform.
+func (c BuildConfig) env() []string {
+	var env []string
+
+	if c.GOOS != "" {
+		env = append(env, "GOOS="+c.GOOS)
+	}
+
+	if c.GOARCH != "" {
+		env = append(env, "GOARCH="+c.GOARCH)
+	}
+
+	return env
+}
+
+// ParseBuildConfigs parses a space-separated list of build configurations.
+//
+// Each configuration has the form "GOOS/GOARCH+tag1+tag2". Either the platform
+// or the tags may be omitted, for example "linux/amd64" or "+prod". The word
+// "default" refers to the server's default environment without any tags.
+func ParseBuildConfigs(s string) ([]BuildConfig, error) {
+	var configs []BuildConfig
+
+	for _, f := range strings.Fields(s) {
+		c, err := parseBuildConfig(f)
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs, c)
+	}
+
+	return configs, nil
+}
+
+func parseBuildConfig(s string) (BuildConfig, error) {
+	var c BuildConfig
+
+	if s == "default" {
+		return c, nil
+	}
+
+	parts := strings.Split(s, "+")
+
+	if p := parts[0]; p != "" {
+		goos, goarch, ok := strings.Cut(p, "/")
+		if !ok || goos == "" || goarch == "" {
+			return BuildConfig{}, fmt.Errorf("invalid build configuration %q: platform must be in GOOS/GOARCH format", s)
+		}
+
+		c.GOOS = goos
+		c.GOARCH = goarch
+	}
+
+	for _, t := range parts[1:] {
+		if t == "" {
+			return BuildConfig{}, fmt.Errorf("invalid build configuration %q: build tags must not be empty", s)
+		}
+
+		c.Tags = append(c.Tags, t)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,299 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/google/go-github/v38/github"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the name of the file in the root of a repository that
+// configures how the repository is analyzed.
+const configFile = ".dogmabrowser.yaml"
+
+// config is the per-repository configuration read from configFile.
+type config struct {
+	// Ignore, if true, excludes the repository from analysis entirely.
+	Ignore bool `yaml:"ignore"`
+
+	// Packages is a list of package patterns to load, relative to the root of
+	// the repository, such as "./cmd/...". If it is empty, all packages within
+	// each module are loaded.
+	Packages []string `yaml:"packages"`
+
+	// BuildTags is a list of build tags to use when loading packages, in
+	// addition to the tags of each build configuration.
+	BuildTags []string `yaml:"build_tags"`
+
+	// BuildConfigs is the set of build configurations under which packages are
+	// loaded. If it is empty, the server-wide build configurations are used.
+	BuildConfigs []BuildConfig `yaml:"build_configs"`
+
+	// Exclude is a list of import path patterns of packages that are not
+	// analyzed, such as "example.org/project/internal/testing/...".
+	Exclude []string `yaml:"exclude"`
+
+	// Team is the name of the team that owns the repository.
+	Team string `yaml:"team"`
+
+	// Description is a human-readable description of the repository.
+	Description string `yaml:"description"`
+
+	// Tags is a list of arbitrary labels used to categorize the repository.
+	Tags []string `yaml:"tags"`
+
+	// Environments is the list of deployment environments to which the
+	// repository is deployed, in the order they are displayed.
+	Environments []environmentConfig `yaml:"environments"`
+}
+
+// environmentConfig is the configuration of a deployment environment.
+type environmentConfig struct {
+	// Name is the name of the environment, such as "production".
+	Name string `yaml:"name"`
+
+	// Ref is a pattern that matches the ref deployed to the environment, such
+	// as "refs/heads/main" or "refs/tags/v*". A pattern that does not begin
+	// with "refs/" is treated as a branch name.
+	//
+	// If the pattern matches more than one ref, the tag with the highest
+	// semantic version is used, falling back to the greatest ref name.
+	Ref string `yaml:"ref"`
+}
+
+// validate returns an error if the configuration is invalid in a way that can
+// not be detected when it is decoded.
+func (c config) validate() error {
+	names := map[string]bool{}
+
+	for _, e := range c.Environments {
+		if e.Name == "" {
+			return errors.New("environment name must not be empty")
+		}
+
+		if e.Ref == "" {
+			return fmt.Errorf("ref pattern for the %q environment must not be empty", e.Name)
+		}
+
+		if _, err := path.Match(e.Ref, ""); err != nil {
+			return fmt.Errorf("ref pattern for the %q environment is invalid: %w", e.Name, err)
+		}
+
+		if names[e.Name] {
+			return fmt.Errorf("environment names must be unique, %q is used more than once", e.Name)
+		}
+
+		names[e.Name] = true
+	}
+
+	return nil
+}
+
+// Metadata returns the descriptive metadata defined by the configuration.
+func (c config) Metadata() persistence.RepositoryMetadata {
+	return persistence.RepositoryMetadata{
+		Team:        strings.TrimSpace(c.Team),
+		Description: strings.TrimSpace(c.Description),
+		Tags:        c.Tags,
+	}
+}
+
+// Builds returns the build configurations under which the repository's
+// packages are loaded.
+//
+// defaults is the server-wide set of build configurations.
+func (c config) Builds(defaults []BuildConfig) []BuildConfig {
+	builds := c.BuildConfigs
+	if len(builds) == 0 {
+		builds = defaults
+	}
+	if len(builds) == 0 {
+		builds = []BuildConfig{{}}
+	}
+
+	var result []BuildConfig
+	for _, b := range builds {
+		b.Tags = append(append([]string(nil), b.Tags...), c.BuildTags...)
+		result = append(result, b)
+	}
+
+	return result
+}
+
+// IsExcluded returns true if the package with the given import path should not
+// be analyzed.
+func (c config) IsExcluded(pkgPath string) bool {
+	for _, p := range c.Exclude {
+		if prefix, ok := strings.CutSuffix(p, "/..."); ok {
+			if pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/") {
+				return true
+			}
+		} else if pkgPath == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Patterns returns the package patterns to load for the modules in u.
+//
+// dir is the directory containing the repository contents, and mods is all of
+// the modules within the repository. Patterns that refer to packages within a
+// nested module are only used when loading that module.
+//
+// It returns nil if none of the configured patterns refer to packages within
+// the modules in u.
+func (c config) Patterns(u loadUnit, dir string, mods []module) []string {
+	if len(c.Packages) == 0 {
+		return u.Patterns()
+	}
+
+	// owner returns the directory of the innermost module that contains the
+	// directory d.
+	owner := func(d string) string {
+		var o string
+		for _, m := range mods {
+			if isWithin(d, m.Dir) && len(m.Dir) > len(o) {
+				o = m.Dir
+			}
+		}
+		return o
+	}
+
+	var (
+		patterns []string
+		seen     = map[string]bool{}
+	)
+
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			patterns = append(patterns, p)
+		}
+	}
+
+	for _, p := range c.Packages {
+		p = filepath.ToSlash(p)
+		base, recursive := strings.CutSuffix(p, "/...")
+		if p == "..." {
+			base, recursive = ".", true
+		}
+
+		base = filepath.Join(dir, filepath.FromSlash(base))
+		if !isWithin(base, dir) {
+			continue
+		}
+
+		for _, m := range u.Modules {
+			if recursive && isWithin(m.Dir, base) {
+				// The pattern matches the entire module.
+				add(m.Dir + "/...")
+			} else if owner(base) == m.Dir {
+				if recursive {
+					add(base + "/...")
+				} else {
+					add(base)
+				}
+			}
+		}
+	}
+
+	return patterns
+}
+
+// isWithin returns true if the directory d is equal to, or a descendant of,
+// the directory parent.
+func isWithin(d, parent string) bool {
+	rel, err := filepath.Rel(parent, d)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, "../"))
+}
+
+// loadConfig reads the repository's configuration file at the given commit.
+//
+// If the repository does not have a configuration file, it returns the default
+// configuration. If the configuration file is invalid, it returns the default
+// configuration and a diagnostic is added to res.
+func (a *Analyzer) loadConfig(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	commit string,
+	res *analysis,
+) (config, error) {
+	content, _, resp, err := c.Repositories.GetContents(
+		ctx,
+		r.GetOwner().GetLogin(),
+		r.GetName(),
+		configFile,
+		&github.RepositoryContentGetOptions{
+			Ref: commit,
+		},
+	)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return config{}, nil
+		}
+
+		return config{}, err
+	}
+
+	data, err := content.GetContent()
+	if err != nil {
+		return config{}, err
+	}
+
+	var cfg config
+
+	dec := yaml.NewDecoder(strings.NewReader(data))
+	dec.KnownFields(true)
+
+	err = dec.Decode(&cfg)
+	if errors.Is(err, io.EOF) {
+		err = nil
+	} else if err == nil {
+		err = cfg.validate()
+	}
+
+	if err != nil {
+		logging.Log(
+			a.Logger,
+			"[#%d %s] ignoring %s, file is invalid: %s",
+			r.GetID(),
+			r.GetFullName(),
+			configFile,
+			err,
+		)
+
+		res.addDiagnostic(persistence.Diagnostic{
+			Category: persistence.ConfigErrorDiagnostic,
+			Position: "/" + configFile,
+			Message:  fmt.Sprintf("configuration file is invalid: %s", err),
+		})
+
+		return config{}, nil
+	}
+
+	logging.Log(
+		a.Logger,
+		"[#%d %s] loaded configuration from %s",
+		r.GetID(),
+		r.GetFullName(),
+		configFile,
+	)
+
+	return cfg, nil
+}
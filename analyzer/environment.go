@@ -0,0 +1,156 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/google/go-github/v38/github"
+)
+
+// analyzeEnvironments resolves the ref deployed to each of the deployment
+// environments defined by cfg, analyzes the commits that they refer to and
+// records the result.
+//
+// cfg is the configuration at the head of the repository's default branch.
+func (a *Analyzer) analyzeEnvironments(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	cfg config,
+	run *persistence.AnalysisRun,
+) error {
+	if reason := skipReason(r, cfg); reason != "" || len(cfg.Environments) == 0 {
+		return persistence.SyncEnvironments(ctx, a.DB, r, nil)
+	}
+
+	refs, err := a.listRefs(ctx, c, r)
+	if err != nil {
+		return fmt.Errorf("unable to list refs: %w", err)
+	}
+
+	var envs []persistence.Environment
+
+	for _, ec := range cfg.Environments {
+		env := persistence.Environment{
+			Name:    ec.Name,
+			Pattern: ec.Ref,
+		}
+
+		for _, ref := range refs {
+			if persistence.MatchRef(ec.Ref, ref.GetRef()) {
+				if env.Ref == "" || compareRefs(ref.GetRef(), env.Ref) > 0 {
+					env.Ref = ref.GetRef()
+				}
+			}
+		}
+
+		if env.Ref == "" {
+			logging.Log(
+				a.Logger,
+				"[#%d %s] no refs match the %s pattern of the %s environment",
+				r.GetID(),
+				r.GetFullName(),
+				ec.Ref,
+				ec.Name,
+			)
+		} else {
+			commit, err := a.resolveRef(ctx, c, r, refs[env.Ref])
+			if err != nil {
+				return fmt.Errorf("unable to resolve %s: %w", env.Ref, err)
+			}
+
+			desc := fmt.Sprintf("%s environment (%s)", ec.Name, env.Ref)
+			if err := a.snapshotCommit(ctx, c, r, desc, commit, run); err != nil {
+				return fmt.Errorf("unable to analyze %s environment: %w", ec.Name, err)
+			}
+
+			env.CommitHash = commit
+		}
+
+		envs = append(envs, env)
+	}
+
+	return persistence.SyncEnvironments(ctx, a.DB, r, envs)
+}
+
+// compareRefs compares two fully-qualified refs to determine which one to use
+// when both match an environment's ref pattern.
+//
+// Refs are compared by their semantic version, if any, then by name.
+func compareRefs(a, b string) int {
+	if c := compareVersionTags(a, b); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a, b)
+}
+
+// listRefs returns the branches and tags of the repository, keyed by their
+// fully-qualified ref.
+func (a *Analyzer) listRefs(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+) (map[string]*github.Reference, error) {
+	refs := map[string]*github.Reference{}
+	opts := &github.ReferenceListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	for {
+		page, res, err := c.Git.ListMatchingRefs(
+			ctx,
+			r.GetOwner().GetLogin(),
+			r.GetName(),
+			opts,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range page {
+			if strings.HasPrefix(ref.GetRef(), "refs/heads/") ||
+				strings.HasPrefix(ref.GetRef(), "refs/tags/") {
+				refs[ref.GetRef()] = ref
+			}
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+
+		opts.Page = res.NextPage
+	}
+
+	return refs, nil
+}
+
+// resolveRef returns the hash of the commit that ref refers to, peeling
+// annotated tags as necessary.
+func (a *Analyzer) resolveRef(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	ref *github.Reference,
+) (string, error) {
+	obj := ref.GetObject()
+
+	for obj.GetType() == "tag" {
+		tag, _, err := c.Git.GetTag(
+			ctx,
+			r.GetOwner().GetLogin(),
+			r.GetName(),
+			obj.GetSHA(),
+		)
+		if err != nil {
+			return "", err
+		}
+
+		obj = tag.GetObject()
+	}
+
+	return obj.GetSHA(), nil
+}
@@ -0,0 +1,77 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+
+	"github.com/dogmatiq/browser/persistence"
+	"golang.org/x/tools/go/packages"
+)
+
+// structFields returns the fields of the type declared by s, or nil if it is
+// not a struct type. It returns an empty, non-nil slice for an empty struct.
+func structFields(pkg *packages.Package, s *ast.TypeSpec) []persistence.Field {
+	st, ok := s.Type.(*ast.StructType)
+	if !ok {
+		return nil
+	}
+
+	fields := []persistence.Field{}
+
+	for _, f := range st.Fields.List {
+		ft := pkg.TypesInfo.TypeOf(f.Type)
+		if ft == nil {
+			continue
+		}
+
+		typeName := types.TypeString(ft, nil)
+
+		var tag string
+		if f.Tag != nil {
+			if t, err := strconv.Unquote(f.Tag.Value); err == nil {
+				tag = t
+			}
+		}
+
+		docs := f.Doc.Text()
+		if docs == "" {
+			docs = f.Comment.Text()
+		}
+
+		if len(f.Names) == 0 {
+			fields = append(fields, persistence.Field{
+				Name:       embeddedName(ft),
+				Type:       typeName,
+				Tag:        tag,
+				Docs:       docs,
+				IsEmbedded: true,
+			})
+			continue
+		}
+
+		for _, n := range f.Names {
+			fields = append(fields, persistence.Field{
+				Name: n.Name,
+				Type: typeName,
+				Tag:  tag,
+				Docs: docs,
+			})
+		}
+	}
+
+	return fields
+}
+
+// embeddedName returns the name of an embedded field of type t.
+func embeddedName(t types.Type) string {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+
+	if n, ok := t.(*types.Named); ok {
+		return n.Obj().Name()
+	}
+
+	return types.TypeString(t, nil)
+}
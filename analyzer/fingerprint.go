@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/dodeca/logging"
+)
+
+// fingerprintModules is the set of modules whose versions affect the results
+// of analysis.
+var fingerprintModules = []string{
+	"github.com/dogmatiq/configkit",
+	"github.com/dogmatiq/dogma",
+	"golang.org/x/tools",
+}
+
+// resultsRevision is incremented whenever the way in which analysis results are
+// stored changes, such that results stored by an older analyzer are replaced.
+const resultsRevision = 5
+
+// Fingerprint returns a value that identifies the behavior of the analyzer.
+//
+// It changes whenever the browser is upgraded, or is built with different
+// versions of the modules that perform the analysis, or is configured with
+// different default build configurations. Repositories that were analyzed by
+// an analyzer with a different fingerprint are analyzed again.
+func (a *Analyzer) Fingerprint() string {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "browser %s\n", a.Version)
+	fmt.Fprintf(h, "results %d\n", resultsRevision)
+	fmt.Fprintf(h, "go %s\n", runtime.Version())
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, m := range fingerprintModules {
+			for _, dep := range info.Deps {
+				if dep.Path == m {
+					fmt.Fprintf(h, "%s %s\n", dep.Path, dep.Version)
+				}
+			}
+		}
+	}
+
+	for _, b := range a.BuildConfigs {
+		fmt.Fprintf(h, "build %s\n", b)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// markOutdatedResultsStale marks the analysis results of each repository that
+// was analyzed by an analyzer with a different fingerprint as stale.
+func (a *Analyzer) markOutdatedResultsStale(ctx context.Context) error {
+	n, err := persistence.MarkOutdatedRepositoriesStale(ctx, a.DB, a.Fingerprint())
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		logging.Log(
+			a.Logger,
+			"marked %d repositories analyzed by a different version of the analyzer as stale",
+			n,
+		)
+	}
+
+	return nil
+}
+
+// analyzerInfo returns information that identifies the analyzer, which is
+// stored with each repository it analyzes.
+func (a *Analyzer) analyzerInfo() persistence.AnalyzerInfo {
+	return persistence.AnalyzerInfo{
+		Version:     a.Version,
+		Fingerprint: a.Fingerprint(),
+	}
+}
@@ -0,0 +1,412 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/google/go-github/v38/github"
+	"golang.org/x/mod/modfile"
+)
+
+// module is a Go module within a repository.
+type module struct {
+	// Path is the module path, as declared in the go.mod file.
+	Path string
+
+	// Dir is the absolute path of the directory containing the go.mod file.
+	Dir string
+}
+
+// workspace is a go.work workspace within a repository.
+type workspace struct {
+	// File is the absolute path of the go.work file.
+	File string
+
+	// Uses is the absolute paths of the module directories listed in the
+	// go.work file's "use" directives.
+	Uses []string
+}
+
+// loadUnit is a set of modules whose packages are loaded together.
+type loadUnit struct {
+	// Dir is the directory in which the go tool is run.
+	Dir string
+
+	// GoWork is the value of the GOWORK environment variable, either the path
+	// to a go.work file, or "off" to disable workspace mode.
+	GoWork string
+
+	// Modules is the modules to load.
+	Modules []module
+}
+
+// Patterns returns the package patterns to load.
+func (u loadUnit) Patterns() []string {
+	if u.GoWork == "off" {
+		return []string{"./..."}
+	}
+
+	var patterns []string
+	for _, m := range u.Modules {
+		patterns = append(patterns, m.Path+"/...")
+	}
+
+	return patterns
+}
+
+// String returns a human-readable description of the unit.
+func (u loadUnit) String() string {
+	var paths []string
+	for _, m := range u.Modules {
+		paths = append(paths, m.Path)
+	}
+
+	if u.GoWork == "off" {
+		return "module " + strings.Join(paths, ", ")
+	}
+
+	return "workspace of modules " + strings.Join(paths, ", ")
+}
+
+// isIgnoredDir returns true if the go tool ignores directories with the given
+// name when matching package patterns, or if the directory is otherwise not
+// expected to contain modules that should be analyzed.
+func isIgnoredDir(name string) bool {
+	return name == "vendor" ||
+		name == "testdata" ||
+		strings.HasPrefix(name, ".") ||
+		strings.HasPrefix(name, "_")
+}
+
+// hasGoModFile returns true if the repository may contain a go.mod file at the
+// given commit.
+//
+// It inspects the repository's tree using the GitHub API so that repositories
+// that do not contain any Go modules do not need to be downloaded. If the tree
+// is too large to be returned in its entirety it conservatively returns true.
+func (a *Analyzer) hasGoModFile(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	commit string,
+) (bool, error) {
+	tree, _, err := c.Git.GetTree(
+		ctx,
+		r.GetOwner().GetLogin(),
+		r.GetName(),
+		commit,
+		true,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if tree.GetTruncated() {
+		return true, nil
+	}
+
+entries:
+	for _, e := range tree.Entries {
+		if e.GetType() != "blob" || path.Base(e.GetPath()) != "go.mod" {
+			continue
+		}
+
+		for _, name := range strings.Split(path.Dir(e.GetPath()), "/") {
+			if isIgnoredDir(name) && name != "." {
+				continue entries
+			}
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// findModules returns the Go modules and workspaces within the repository
+// contents in dir.
+//
+// Modules that can not be analyzed, such as those with invalid go.mod files,
+// are excluded from the result and a diagnostic is added to res instead.
+func (a *Analyzer) findModules(
+	r *github.Repository,
+	dir string,
+	res *analysis,
+) ([]module, []workspace, error) {
+	var (
+		mods  []module
+		works []workspace
+	)
+
+	err := filepath.WalkDir(
+		dir,
+		func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				if p != dir && isIgnoredDir(d.Name()) {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			switch d.Name() {
+			case "go.mod":
+				m, reason, err := a.parseModule(r, dir, p)
+				if err != nil {
+					return err
+				}
+
+				if reason != "" {
+					res.addDiagnostic(persistence.Diagnostic{
+						Category: persistence.SkippedDiagnostic,
+						Position: strings.TrimPrefix(p, dir),
+						Message:  reason,
+					})
+
+					return nil
+				}
+
+				mods = append(mods, m)
+
+			case "go.work":
+				w, ok, err := a.parseWorkspace(r, dir, p, res)
+				if err != nil {
+					return err
+				}
+
+				if ok {
+					works = append(works, w)
+				}
+			}
+
+			return nil
+		},
+	)
+
+	return mods, works, err
+}
+
+// parseModule parses the go.mod file at the given path.
+//
+// If the module can not be analyzed, reason is a human-readable explanation of
+// why.
+func (a *Analyzer) parseModule(
+	r *github.Repository,
+	dir, file string,
+) (m module, reason string, err error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return module{}, "", err
+	}
+
+	rel := strings.TrimPrefix(file, dir)
+
+	mod, err := modfile.ParseLax(rel, data, nil)
+	if err != nil {
+		logging.Log(
+			a.Logger,
+			"[#%d %s] skipping analysis of %s, go.mod file is invalid: %s",
+			r.GetID(),
+			r.GetFullName(),
+			rel,
+			err,
+		)
+
+		return module{}, fmt.Sprintf("go.mod file is invalid: %s", err), nil
+	}
+
+	if mod.Module == nil {
+		logging.Log(
+			a.Logger,
+			"[#%d %s] skipping analysis of %s, go.mod file does not declare a module path",
+			r.GetID(),
+			r.GetFullName(),
+			rel,
+		)
+
+		return module{}, "go.mod file does not declare a module path", nil
+	}
+
+	if mod.Module.Mod.Path == "github.com/dogmatiq/dogma" {
+		logging.Log(
+			a.Logger,
+			"[#%d %s] skipping analysis of %s, found dogma module: %s",
+			r.GetID(),
+			r.GetFullName(),
+			rel,
+			mod.Module.Mod.Path,
+		)
+
+		return module{}, "found dogma module", nil
+	}
+
+	logging.Log(
+		a.Logger,
+		"[#%d %s] found module %s in %s",
+		r.GetID(),
+		r.GetFullName(),
+		mod.Module.Mod.Path,
+		rel,
+	)
+
+	return module{
+		Path: mod.Module.Mod.Path,
+		Dir:  filepath.Dir(file),
+	}, "", nil
+}
+
+// parseWorkspace parses the go.work file at the given path.
+//
+// If the file is invalid it returns false and a diagnostic is added to res.
+func (a *Analyzer) parseWorkspace(
+	r *github.Repository,
+	dir, file string,
+	res *analysis,
+) (workspace, bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return workspace{}, false, err
+	}
+
+	rel := strings.TrimPrefix(file, dir)
+
+	work, err := modfile.ParseWork(rel, data, nil)
+	if err != nil {
+		logging.Log(
+			a.Logger,
+			"[#%d %s] ignoring %s, go.work file is invalid: %s",
+			r.GetID(),
+			r.GetFullName(),
+			rel,
+			err,
+		)
+
+		res.addDiagnostic(persistence.Diagnostic{
+			Category: persistence.LoadErrorDiagnostic,
+			Position: rel,
+			Message:  fmt.Sprintf("go.work file is invalid: %s", err),
+		})
+
+		return workspace{}, false, nil
+	}
+
+	w := workspace{
+		File: file,
+	}
+
+	for _, u := range work.Use {
+		w.Uses = append(
+			w.Uses,
+			filepath.Join(filepath.Dir(file), filepath.FromSlash(u.Path)),
+		)
+	}
+
+	logging.Log(
+		a.Logger,
+		"[#%d %s] found workspace of %d module(s) in %s",
+		r.GetID(),
+		r.GetFullName(),
+		len(w.Uses),
+		rel,
+	)
+
+	return w, true, nil
+}
+
+// planLoads groups the modules within the repository contents in dir into
+// units that are loaded together.
+//
+// The modules used by each valid workspace are loaded together, such that
+// references between them are resolved the same way as they would be for a
+// developer working within that workspace. Every other module is loaded on its
+// own, with workspace mode disabled.
+//
+// A workspace that uses a module that can not be analyzed, such as a module
+// outside the repository, can not be loaded. Its modules are loaded on their
+// own instead, and a diagnostic is added to res.
+func (a *Analyzer) planLoads(
+	r *github.Repository,
+	dir string,
+	mods []module,
+	works []workspace,
+	res *analysis,
+) []loadUnit {
+	byDir := map[string]module{}
+	for _, m := range mods {
+		byDir[m.Dir] = m
+	}
+
+	var units []loadUnit
+	inWorkspace := map[string]bool{}
+
+works:
+	for _, w := range works {
+		u := loadUnit{
+			Dir:    filepath.Dir(w.File),
+			GoWork: w.File,
+		}
+
+		for _, d := range w.Uses {
+			m, ok := byDir[d]
+			if !ok || inWorkspace[d] {
+				rel := strings.TrimPrefix(w.File, dir)
+				reason := fmt.Sprintf(
+					"go.work file uses a module that can not be analyzed as part of this workspace: %s",
+					strings.TrimPrefix(d, dir),
+				)
+
+				logging.Log(
+					a.Logger,
+					"[#%d %s] ignoring %s, %s",
+					r.GetID(),
+					r.GetFullName(),
+					rel,
+					reason,
+				)
+
+				res.addDiagnostic(persistence.Diagnostic{
+					Category: persistence.LoadErrorDiagnostic,
+					Position: rel,
+					Message:  reason,
+				})
+
+				continue works
+			}
+
+			u.Modules = append(u.Modules, m)
+		}
+
+		if len(u.Modules) == 0 {
+			continue
+		}
+
+		for _, m := range u.Modules {
+			inWorkspace[m.Dir] = true
+		}
+
+		units = append(units, u)
+	}
+
+	for _, m := range mods {
+		if !inWorkspace[m.Dir] {
+			units = append(units, loadUnit{
+				Dir:     m.Dir,
+				GoWork:  "off",
+				Modules: []module{m},
+			})
+		}
+	}
+
+	return units
+}
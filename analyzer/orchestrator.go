@@ -2,70 +2,381 @@ package analyzer
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"sync"
+	"time"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/dogmatiq/linger"
+	"golang.org/x/sync/errgroup"
 )
 
 // Orchestrator orchestrates the analysis and removal of repositories.
+//
+// Work is queued in the database so that it survives restarts of the browser.
 type Orchestrator struct {
+	DB       *sql.DB
 	Analyzer *Analyzer
 	Remover  *Remover
+	Logger   logging.Logger
 
-	once  sync.Once
-	queue chan queueItem
-}
+	// Concurrency is the maximum number of jobs to process concurrently. If it
+	// is non-positive, a value of 1 is used.
+	//
+	// Jobs for different repositories are processed in parallel, but jobs for
+	// the same repository are always processed sequentially, in the order they
+	// were enqueued.
+	Concurrency int
+
+	// PollInterval is the interval at which the queue is checked for new jobs
+	// when there is no indication that a job has been enqueued. If it is
+	// non-positive, a value of 30 seconds is used.
+	//
+	// Jobs enqueued via this orchestrator are processed immediately; polling
+	// is only necessary to find jobs enqueued elsewhere, or jobs with expired
+	// claims.
+	PollInterval time.Duration
+
+	// ClaimTimeout is the amount of time that a job may go without a heartbeat
+	// from the worker processing it before it is made available to be claimed
+	// again. The claim is extended periodically while the job is processed. If
+	// it is non-positive, a value of 1 hour is used.
+	ClaimTimeout time.Duration
+
+	// MaxAttempts is the number of times a job is attempted before it is moved
+	// to the dead-letter list. If it is non-positive, a value of 5 is used.
+	//
+	// Jobs that fail with errors that are not expected to be resolved by
+	// retrying are moved to the dead-letter list immediately.
+	MaxAttempts int
 
-type queueItem struct {
-	repoID int64
-	remove bool
+	// RetryDelay is the delay before a failed job is first retried. The delay
+	// doubles with each subsequent attempt, up to a maximum of 1 hour. If it is
+	// non-positive, a value of 1 minute is used.
+	RetryDelay time.Duration
+
+	once  sync.Once
+	ready chan struct{}
 }
 
 // Run performs analysis and removal until ctx is cancelled or an error occurs.
+//
+// Jobs that fail are retried with an exponential backoff. They do not cause Run
+// to return an error.
 func (o *Orchestrator) Run(ctx context.Context) error {
 	o.init()
 
+	n := o.Concurrency
+	if n <= 0 {
+		n = 1
+	}
+
+	if err := o.Analyzer.markOutdatedResultsStale(ctx); err != nil {
+		return err
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			return o.work(ctx)
+		})
+	}
+
+	return g.Wait()
+}
+
+// work claims and handles jobs until ctx is cancelled or an error occurs.
+func (o *Orchestrator) work(ctx context.Context) error {
 	for {
+		ok, err := o.tick(ctx)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			continue
+		}
+
+		// Stale repositories are only re-analyzed when there is no other work
+		// to do, so that invalidating the results of every repository does not
+		// delay analysis that was triggered by a change.
+		ok, err = o.enqueueStale(ctx)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			continue
+		}
+
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case item := <-o.queue:
-			if err := o.handle(ctx, item); err != nil {
-				return err
-			}
+		case <-o.ready:
+		case <-time.After(linger.MustCoalesce(o.PollInterval, 30*time.Second)):
 		}
 	}
 }
 
 // EnqueueAnalyis enqueues a repository for analysis.
-func (o *Orchestrator) EnqueueAnalyis(ctx context.Context, repoID int64) error {
-	return o.enqueue(ctx, repoID, false)
+//
+// t is the reason the analysis is being requested, which determines the
+// priority of the job. If the repository is already waiting to be analyzed the
+// existing job is reused, and its priority is raised if necessary.
+func (o *Orchestrator) EnqueueAnalyis(
+	ctx context.Context,
+	repoID int64,
+	t persistence.Trigger,
+) error {
+	return o.enqueue(ctx, repoID, persistence.AnalyzeOperation, t)
+}
+
+// RetryDeadLetter moves the job with the given ID from the dead-letter list
+// back onto the queue.
+//
+// It returns false if there is no such job on the dead-letter list.
+func (o *Orchestrator) RetryDeadLetter(ctx context.Context, id int64) (bool, error) {
+	o.init()
+
+	ok, err := persistence.RetryDeadLetter(
+		ctx,
+		o.DB,
+		id,
+		persistence.ManualTrigger,
+	)
+	if ok {
+		o.wake()
+	}
+
+	return ok, err
 }
 
 // EnqueueRemoval enqueues a repository for removal.
-func (o *Orchestrator) EnqueueRemoval(ctx context.Context, repoID int64) error {
-	return o.enqueue(ctx, repoID, true)
+//
+// t is the reason the removal is being requested. Removals are always
+// processed with interactive priority.
+func (o *Orchestrator) EnqueueRemoval(
+	ctx context.Context,
+	repoID int64,
+	t persistence.Trigger,
+) error {
+	return o.enqueue(
+		ctx,
+		repoID,
+		persistence.RemoveOperation,
+		t,
+	)
 }
 
-func (o *Orchestrator) handle(ctx context.Context, item queueItem) error {
-	if item.remove {
-		return o.Remover.Remove(ctx, item.repoID)
+// tick claims and handles a single job. It returns false if there are no jobs
+// available to be claimed.
+func (o *Orchestrator) tick(ctx context.Context) (bool, error) {
+	timeout := linger.MustCoalesce(o.ClaimTimeout, 1*time.Hour)
+
+	job, ok, err := persistence.ClaimJob(ctx, o.DB, timeout)
+	if !ok || err != nil {
+		return false, err
 	}
 
-	return o.Analyzer.Analyze(ctx, item.repoID)
+	// Wake another worker, as there may be more jobs that can be processed in
+	// parallel with this one.
+	o.wake()
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		o.heartbeat(jobCtx, cancel, job, timeout)
+	}()
+
+	err = o.handle(jobCtx, job)
+
+	cancel(nil)
+	<-done
+
+	// The job has been claimed by another worker, which is now responsible
+	// for it.
+	if context.Cause(jobCtx) == errClaimLost {
+		return true, nil
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		return true, o.fail(ctx, job, err)
+	}
+
+	return true, persistence.CompleteJob(ctx, o.DB, job)
+}
+
+// errClaimLost is the cause of the cancelation of a job's context when the
+// claim on the job is lost.
+var errClaimLost = errors.New("claim on job was lost")
+
+// heartbeat extends the claim on a job at regular intervals until ctx is
+// canceled, such that the job is not claimed by another worker while it is
+// still being processed.
+//
+// If the claim is lost, such as when the heartbeat was delayed for longer than
+// the timeout, cancel is called with errClaimLost so that processing stops.
+func (o *Orchestrator) heartbeat(
+	ctx context.Context,
+	cancel context.CancelCauseFunc,
+	job persistence.Job,
+	timeout time.Duration,
+) {
+	ticker := time.NewTicker(timeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ok, err := persistence.ExtendClaim(ctx, o.DB, job, timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// The claim may still be held, so processing continues. The claim
+			// is extended again at the next interval.
+			logging.Log(
+				o.Logger,
+				"[#%d] %s: %s",
+				job.RepositoryID,
+				job.Operation,
+				err,
+			)
+
+			continue
+		}
+
+		if !ok {
+			logging.Log(
+				o.Logger,
+				"[#%d] %s abandoned, the claim on the job has expired",
+				job.RepositoryID,
+				job.Operation,
+			)
+
+			cancel(errClaimLost)
+			return
+		}
+	}
+}
+
+// fail schedules a failed job to be retried, or moves it to the dead-letter
+// list if it has been attempted too many times or is not expected to succeed
+// when retried.
+func (o *Orchestrator) fail(
+	ctx context.Context,
+	job persistence.Job,
+	cause error,
+) error {
+	attempt := job.Attempts + 1
+
+	maxAttempts := o.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+
+	delay, ok := retryDelay(
+		cause,
+		attempt,
+		linger.MustCoalesce(o.RetryDelay, 1*time.Minute),
+	)
+
+	if ok && attempt < maxAttempts {
+		logging.Log(
+			o.Logger,
+			"[#%d] %s failed (attempt %d of %d), retrying in %s: %s",
+			job.RepositoryID,
+			job.Operation,
+			attempt,
+			maxAttempts,
+			delay.Round(time.Second),
+			cause,
+		)
+
+		return persistence.RetryJob(ctx, o.DB, job, delay, cause)
+	}
+
+	logging.Log(
+		o.Logger,
+		"[#%d] %s failed (attempt %d), moved to dead-letter list: %s",
+		job.RepositoryID,
+		job.Operation,
+		attempt,
+		cause,
+	)
+
+	return persistence.DeadLetterJob(ctx, o.DB, job, cause)
+}
+
+func (o *Orchestrator) handle(ctx context.Context, job persistence.Job) error {
+	if job.Operation == persistence.RemoveOperation {
+		return o.Remover.Remove(ctx, job.RepositoryID)
+	}
+
+	return o.Analyzer.Analyze(ctx, job.RepositoryID, job.Trigger)
 }
 
-func (o *Orchestrator) enqueue(ctx context.Context, repoID int64, remove bool) error {
+// enqueueStale enqueues analysis of a single repository that has been marked
+// as stale. It returns false if there are no stale repositories awaiting
+// analysis.
+func (o *Orchestrator) enqueueStale(ctx context.Context) (bool, error) {
+	repoID, ok, err := persistence.NextStaleRepository(ctx, o.DB)
+	if !ok || err != nil {
+		return false, err
+	}
+
+	logging.Log(
+		o.Logger,
+		"[#%d] repository analysis is stale, enqueuing analysis",
+		repoID,
+	)
+
+	return true, o.enqueue(ctx, repoID, persistence.AnalyzeOperation, persistence.StaleTrigger)
+}
+
+func (o *Orchestrator) enqueue(
+	ctx context.Context,
+	repoID int64,
+	op persistence.Operation,
+	t persistence.Trigger,
+) error {
 	o.init()
 
+	if err := persistence.EnqueueJob(ctx, o.DB, repoID, op, t); err != nil {
+		return err
+	}
+
+	o.wake()
+
+	return nil
+}
+
+// wake signals an idle worker to check the queue for jobs, if one is not
+// already awake.
+func (o *Orchestrator) wake() {
 	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case o.queue <- queueItem{repoID, remove}:
-		return nil
+	case o.ready <- struct{}{}:
+	default:
 	}
 }
 
 func (o *Orchestrator) init() {
 	o.once.Do(func() {
-		o.queue = make(chan queueItem, 100)
+		o.ready = make(chan struct{}, 1)
 	})
 }
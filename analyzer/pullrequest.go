@@ -0,0 +1,284 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/google/go-github/v38/github"
+)
+
+// checkRunName is the name of the GitHub check run that reports the results
+// of the analysis of a pull request.
+const checkRunName = "Dogma Browser"
+
+// maxCheckRunSummary is the maximum length of the summary of a check run, as
+// imposed by the GitHub API.
+const maxCheckRunSummary = 65535
+
+// checkPullRequests analyzes the head of each of the repository's open pull
+// requests that have changed since they were last checked, and reports the
+// differences from the base branch as a GitHub check run.
+//
+// A failure to check one pull request does not fail the analysis of the
+// repository. The error is logged and recorded against the pull request, which
+// is checked again the next time the repository is analyzed.
+func (a *Analyzer) checkPullRequests(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	run *persistence.AnalysisRun,
+) error {
+	prs, err := persistence.PendingPullRequests(ctx, a.DB, r.GetID())
+	if err != nil {
+		return err
+	}
+
+	for _, pr := range prs {
+		err := a.checkPullRequest(ctx, c, r, pr, run)
+		if err == nil {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		logging.Log(
+			a.Logger,
+			"[#%d %s] unable to check pull request #%d (%s): %s",
+			r.GetID(),
+			r.GetFullName(),
+			pr.Number,
+			pr.HeadCommitHash,
+			err,
+		)
+
+		if err := persistence.RecordPullRequestError(ctx, a.DB, r.GetID(), pr, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkPullRequest analyzes the head and base of a single pull request and
+// reports the differences between them as a GitHub check run.
+func (a *Analyzer) checkPullRequest(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	pr persistence.PullRequest,
+	run *persistence.AnalysisRun,
+) error {
+	if pr.CheckRunID == 0 {
+		cr, _, err := c.Checks.CreateCheckRun(
+			ctx,
+			r.GetOwner().GetLogin(),
+			r.GetName(),
+			github.CreateCheckRunOptions{
+				Name:    checkRunName,
+				HeadSHA: pr.HeadCommitHash,
+				Status:  github.String("in_progress"),
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("unable to create check run: %w", err)
+		}
+
+		pr.CheckRunID = cr.GetID()
+
+		if err := persistence.SetPullRequestCheckRun(ctx, a.DB, r.GetID(), pr); err != nil {
+			return err
+		}
+	}
+
+	desc := fmt.Sprintf("pull request #%d", pr.Number)
+	if err := a.snapshotCommit(ctx, c, r, desc, pr.HeadCommitHash, run); err != nil {
+		return err
+	}
+
+	desc = pr.BaseRef + " branch"
+	if err := a.snapshotCommit(ctx, c, r, desc, pr.BaseCommitHash, run); err != nil {
+		return err
+	}
+
+	opts := github.UpdateCheckRunOptions{
+		Name:        checkRunName,
+		Status:      github.String("completed"),
+		CompletedAt: &github.Timestamp{Time: time.Now()},
+	}
+
+	reason, err := persistence.SnapshotSkipReason(ctx, a.DB, r.GetID(), pr.HeadCommitHash)
+	if err != nil {
+		return err
+	}
+
+	if reason != "" {
+		opts.Conclusion = github.String("skipped")
+		opts.Output = &github.CheckRunOutput{
+			Title:   github.String("Analysis skipped"),
+			Summary: github.String(fmt.Sprintf("The pull request was not analyzed: %s.", reason)),
+		}
+	} else {
+		diff, err := persistence.DiffSnapshots(
+			ctx,
+			a.DB,
+			r.GetID(),
+			pr.BaseCommitHash,
+			pr.HeadCommitHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		title, summary := formatCheckRun(pr, diff)
+
+		// New mismatches are reported as "neutral" rather than as a failure,
+		// as they may be intentional, for example when a message is being
+		// migrated to a new kind across several repositories.
+		conclusion := "success"
+		if len(diff.NewMismatches) != 0 {
+			conclusion = "neutral"
+		}
+
+		opts.Conclusion = github.String(conclusion)
+		opts.Output = &github.CheckRunOutput{
+			Title:   github.String(title),
+			Summary: github.String(summary),
+		}
+	}
+
+	if _, _, err := c.Checks.UpdateCheckRun(
+		ctx,
+		r.GetOwner().GetLogin(),
+		r.GetName(),
+		pr.CheckRunID,
+		opts,
+	); err != nil {
+		return fmt.Errorf("unable to update check run: %w", err)
+	}
+
+	logging.Log(
+		a.Logger,
+		"[#%d %s] reported analysis of pull request #%d (%s) against %s branch (%s)",
+		r.GetID(),
+		r.GetFullName(),
+		pr.Number,
+		pr.HeadCommitHash,
+		pr.BaseRef,
+		pr.BaseCommitHash,
+	)
+
+	return persistence.MarkPullRequestChecked(ctx, a.DB, r.GetID(), pr)
+}
+
+// formatCheckRun returns the title and Markdown summary of a check run that
+// reports the differences between the head and base of a pull request.
+func formatCheckRun(
+	pr persistence.PullRequest,
+	d persistence.SnapshotDiff,
+) (string, string) {
+	if d.IsEmpty() {
+		return "No changes", fmt.Sprintf(
+			"The pull request does not change any Dogma applications, handlers or messages when compared with the `%s` branch.",
+			pr.BaseRef,
+		)
+	}
+
+	var (
+		parts []string
+		w     strings.Builder
+	)
+
+	fmt.Fprintf(
+		&w,
+		"Compared with the `%s` branch at %s.\n",
+		pr.BaseRef,
+		pr.BaseCommitHash,
+	)
+
+	if n := len(d.AddedHandlers); n != 0 {
+		parts = append(parts, fmt.Sprintf("%d handler(s) added", n))
+
+		w.WriteString("\n### Added handlers\n\n")
+		for _, h := range d.AddedHandlers {
+			fmt.Fprintf(&w, "- **%s** %s in the _%s_ application\n", h.Name, h.HandlerType, h.ApplicationName)
+		}
+	}
+
+	if n := len(d.RemovedHandlers); n != 0 {
+		parts = append(parts, fmt.Sprintf("%d handler(s) removed", n))
+
+		w.WriteString("\n### Removed handlers\n\n")
+		for _, h := range d.RemovedHandlers {
+			fmt.Fprintf(&w, "- **%s** %s in the _%s_ application\n", h.Name, h.HandlerType, h.ApplicationName)
+		}
+	}
+
+	if n := len(d.ChangedKinds); n != 0 {
+		parts = append(parts, fmt.Sprintf("%d message kind(s) changed", n))
+
+		w.WriteString("\n### Changed message kinds\n\n")
+		for _, k := range d.ChangedKinds {
+			fmt.Fprintf(
+				&w,
+				"- `%s.%s` changed from %s to %s\n",
+				k.TypePackage,
+				k.TypeName,
+				formatKinds(k.Before),
+				formatKinds(k.After),
+			)
+		}
+	}
+
+	if n := len(d.NewMismatches); n != 0 {
+		parts = append(parts, fmt.Sprintf("%d new mismatch(es)", n))
+
+		w.WriteString("\n### New mismatches with other repositories\n\n")
+		for _, m := range d.NewMismatches {
+			fmt.Fprintf(
+				&w,
+				"- `%s` is used as %s, but `%s` is used as %s by the _%s_ application in %s\n",
+				formatMessageType(m.TypePackage, m.TypeName, m.IsPointer),
+				m.Kind,
+				formatMessageType(m.TypePackage, m.TypeName, m.OtherIsPointer),
+				m.OtherKind,
+				m.OtherApplication,
+				m.OtherRepository,
+			)
+		}
+	}
+
+	summary := w.String()
+	if len(summary) > maxCheckRunSummary {
+		const suffix = "\n\n_The summary has been truncated._"
+		summary = summary[:maxCheckRunSummary-len(suffix)] + suffix
+	}
+
+	return strings.Join(parts, ", "), summary
+}
+
+// formatKinds returns a human-readable list of message kinds.
+func formatKinds(kinds []message.Kind) string {
+	var names []string
+	for _, k := range kinds {
+		names = append(names, k.String())
+	}
+
+	return strings.Join(names, " and ")
+}
+
+// formatMessageType returns the fully-qualified name of a message type.
+func formatMessageType(pkg, name string, isPointer bool) string {
+	n := pkg + "." + name
+	if isPointer {
+		return "*" + n
+	}
+
+	return n
+}
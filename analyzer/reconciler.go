@@ -0,0 +1,180 @@
+package analyzer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dogmatiq/browser/githubx"
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/dogmatiq/linger"
+	"github.com/google/go-github/v38/github"
+)
+
+// Reconciler periodically compares the repositories that are accessible to the
+// GitHub application with those stored in the database, to correct for any
+// webhook events that were missed.
+type Reconciler struct {
+	DB           *sql.DB
+	Connector    *githubx.Connector
+	Orchestrator *Orchestrator
+	Logger       logging.Logger
+
+	// Interval is the interval at which reconciliation is performed. If it is
+	// non-positive, a value of 1 hour is used.
+	Interval time.Duration
+}
+
+// Run reconciles the repositories immediately, then again at each interval,
+// until ctx is cancelled.
+//
+// Failure to reconcile is logged, it does not cause Run to return an error.
+func (rc *Reconciler) Run(ctx context.Context) error {
+	t := persistence.StartupTrigger
+
+	for {
+		if err := rc.Reconcile(ctx, t); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			logging.Log(rc.Logger, "unable to reconcile repositories: %s", err)
+		}
+
+		t = persistence.ReconcileTrigger
+
+		if err := linger.Sleep(ctx, rc.Interval, time.Hour); err != nil {
+			return err
+		}
+	}
+}
+
+// Reconcile enqueues analysis of each accessible repository that has not been
+// analyzed, or whose default branch has changed since it was analyzed. It
+// enqueues removal of each stored repository that is no longer accessible.
+//
+// t is the trigger recorded against any jobs that are enqueued.
+func (rc *Reconciler) Reconcile(ctx context.Context, t persistence.Trigger) error {
+	commits, err := persistence.LoadAnalyzedCommits(ctx, rc.DB)
+	if err != nil {
+		return err
+	}
+
+	accessible := map[int64]struct{}{}
+
+	if err := githubx.ListInstallations(
+		ctx,
+		rc.Connector.AppClient,
+		func(ctx context.Context, i *github.Installation) error {
+			// Repositories of suspended installations are not accessible, and
+			// are removed in the same way as when the suspension is reported
+			// via a webhook.
+			if i.SuspendedAt != nil {
+				return nil
+			}
+
+			c, err := rc.Connector.InstallationClient(ctx, i.GetID())
+			if err != nil {
+				return err
+			}
+
+			return githubx.ListRepos(
+				ctx,
+				c,
+				func(ctx context.Context, r *github.Repository) error {
+					accessible[r.GetID()] = struct{}{}
+					return rc.reconcileRepository(ctx, c, r, commits, t)
+				},
+			)
+		},
+	); err != nil {
+		return err
+	}
+
+	// Removals are only enqueued once every installation has been listed
+	// successfully, otherwise a transient error could cause accessible
+	// repositories to be removed.
+	for id := range commits {
+		if _, ok := accessible[id]; ok {
+			continue
+		}
+
+		logging.Log(
+			rc.Logger,
+			"[#%d] repository is no longer accessible, enqueuing removal",
+			id,
+		)
+
+		if err := rc.Orchestrator.EnqueueRemoval(ctx, id, t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reconcileRepository enqueues analysis of r if it has not been analyzed, or
+// the head of its default branch has changed since it was analyzed.
+func (rc *Reconciler) reconcileRepository(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	commits map[int64]string,
+	t persistence.Trigger,
+) error {
+	// Repositories that are stale but unchanged are not enqueued here, as
+	// they are analyzed gradually by the orchestrator when it is otherwise
+	// idle.
+	commit, ok := commits[r.GetID()]
+	if !ok {
+		return rc.Orchestrator.EnqueueAnalyis(ctx, r.GetID(), t)
+	}
+
+	// The stored commit hash is used as an ETag, such that GitHub responds
+	// with 304 Not Modified if the branch has not changed. Such responses do
+	// not count against the API rate limit.
+	head, res, err := c.Repositories.GetCommitSHA1(
+		ctx,
+		r.GetOwner().GetLogin(),
+		r.GetName(),
+		"heads/"+r.GetDefaultBranch(),
+		commit,
+	)
+	if err != nil {
+		if res != nil {
+			switch res.StatusCode {
+			case http.StatusNotModified:
+				return nil
+			case http.StatusNotFound, http.StatusConflict:
+				// The repository is empty or its default branch does not exist,
+				// neither of which can be resolved by analyzing it again.
+				return nil
+			}
+		}
+
+		return fmt.Errorf(
+			"unable to fetch %s branch of %s: %w",
+			r.GetDefaultBranch(),
+			r.GetFullName(),
+			err,
+		)
+	}
+
+	if head == commit {
+		return nil
+	}
+
+	logging.Log(
+		rc.Logger,
+		"[#%d %s] %s branch has changed since it was analyzed (%s), enqueuing analysis",
+		r.GetID(),
+		r.GetFullName(),
+		r.GetDefaultBranch(),
+		head,
+	)
+
+	return rc.Orchestrator.EnqueueAnalyis(ctx, r.GetID(), t)
+}
@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v38/github"
+)
+
+// maxRetryDelay is the maximum delay between attempts of a failed job.
+const maxRetryDelay = 1 * time.Hour
+
+// retryDelay returns the amount of time to wait before retrying a job that
+// failed with the given error.
+//
+// ok is false if the error is not transient, in which case retrying the job is
+// not expected to succeed.
+//
+// attempt is the number of times the job has been attempted, including the
+// attempt that failed. The delay doubles with each attempt, starting at base.
+func retryDelay(err error, attempt int, base time.Duration) (_ time.Duration, ok bool) {
+	// Errors caused by the repository's source code, such as an invalid go.mod
+	// file, won't resolve themselves until the repository itself is changed.
+	var analysisErr analysisError
+	if errors.As(err, &analysisErr) {
+		return 0, false
+	}
+
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return time.Until(rateLimitErr.Rate.Reset.Time), true
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if d := abuseErr.GetRetryAfter(); d > 0 {
+			return d, true
+		}
+	} else {
+		var resErr *github.ErrorResponse
+		if errors.As(err, &resErr) && resErr.Response != nil {
+			code := resErr.Response.StatusCode
+
+			// Client errors won't resolve themselves by retrying, with the
+			// exception of "too many requests".
+			if code >= 400 && code < 500 && code != http.StatusTooManyRequests {
+				return 0, false
+			}
+		}
+	}
+
+	// Any other error, such as a server error from GitHub or a database error,
+	// is assumed to be transient.
+	delay := base
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay, true
+}
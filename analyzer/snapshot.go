@@ -0,0 +1,132 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/google/go-github/v38/github"
+)
+
+// snapshotCommit analyzes the given commit of the repository and stores the
+// results as an immutable snapshot, unless the commit has already been
+// snapshotted.
+//
+// If the commit's source can not be analyzed, the snapshot is stored with a
+// skip reason describing the failure, as analyzing the same commit again is
+// not expected to succeed. Only errors communicating with GitHub or the
+// database are returned.
+//
+// ref is a human-readable description of the commit, used in log messages, such
+// as "v1.2.3 tag".
+func (a *Analyzer) snapshotCommit(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	ref, commit string,
+	run *persistence.AnalysisRun,
+) error {
+	ok, err := persistence.SnapshotExists(ctx, a.DB, r.GetID(), commit)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		return nil
+	}
+
+	var res analysis
+
+	cfg, err := a.loadConfig(ctx, c, r, commit, &res)
+	if err != nil {
+		return err
+	}
+
+	reason := skipReason(r, cfg)
+
+	if reason != "" {
+		logging.Log(
+			a.Logger,
+			"[#%d %s] skipping analysis of %s (%s), %s",
+			r.GetID(),
+			r.GetFullName(),
+			ref,
+			commit,
+			reason,
+		)
+	} else {
+		ok, err := a.analyzeSource(ctx, c, r, ref, commit, cfg, run, &res)
+
+		var analysisErr analysisError
+		if errors.As(err, &analysisErr) {
+			logging.Log(
+				a.Logger,
+				"[#%d %s] unable to analyze %s (%s): %s",
+				r.GetID(),
+				r.GetFullName(),
+				ref,
+				commit,
+				err,
+			)
+
+			// Discard any partial results, the snapshot records only that the
+			// commit could not be analyzed.
+			res = analysis{}
+			reason = "analysis failed: " + err.Error()
+		} else if err != nil {
+			return err
+		} else if !ok {
+			reason = noModulesReason
+		}
+	}
+
+	return a.saveSnapshot(ctx, c, r, commit, reason, res.apps, res.defs, run)
+}
+
+// noModulesReason is the skip reason recorded in the snapshot of a commit that
+// does not contain any Go modules that could be analyzed.
+const noModulesReason = "no Go modules could be analyzed"
+
+// saveSnapshot stores the results of analyzing the given commit as an
+// immutable snapshot.
+//
+// reason is the reason the commit was not analyzed, if any.
+func (a *Analyzer) saveSnapshot(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	commit, reason string,
+	apps []persistence.Application,
+	defs []persistence.TypeDef,
+	run *persistence.AnalysisRun,
+) error {
+	gc, _, err := c.Git.GetCommit(
+		ctx,
+		r.GetOwner().GetLogin(),
+		r.GetName(),
+		commit,
+	)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	defer func() {
+		run.SyncDuration += time.Since(start)
+	}()
+
+	return persistence.SaveSnapshot(
+		ctx,
+		a.DB,
+		r,
+		persistence.Snapshot{
+			CommitHash:   commit,
+			CommittedAt:  gc.GetCommitter().GetDate(),
+			SkipReason:   reason,
+			Applications: apps,
+			TypeDefs:     defs,
+		},
+	)
+}
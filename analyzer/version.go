@@ -0,0 +1,191 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/google/go-github/v38/github"
+	"golang.org/x/mod/semver"
+)
+
+// maxVersionsPerJob is the maximum number of versions that are analyzed by a
+// single job. Any remaining versions are analyzed by a subsequent job so that
+// a repository with a long history does not monopolize a worker.
+const maxVersionsPerJob = 5
+
+// isVersionTag returns true if the given tag name is a semantic version,
+// optionally prefixed by the path of a nested module, such as "api/v1.2.3".
+func isVersionTag(tag string) bool {
+	v := tag[strings.LastIndexByte(tag, '/')+1:]
+	return semver.IsValid(v)
+}
+
+// analyzeVersions analyzes each version of the repository that has not already
+// been analyzed.
+//
+// A version is any tag that satisfies isVersionTag, or that is associated with
+// a published GitHub release. Newer versions are analyzed first.
+//
+// A version that can not be analyzed is recorded with a skip reason, such that
+// it does not prevent the remaining versions from being analyzed.
+func (a *Analyzer) analyzeVersions(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+	run *persistence.AnalysisRun,
+) error {
+	if reason := skipReason(r, config{}); reason != "" {
+		return nil
+	}
+
+	versions, err := a.listVersions(ctx, c, r)
+	if err != nil {
+		return fmt.Errorf("unable to list versions: %w", err)
+	}
+
+	done, err := persistence.SnapshottedVersions(ctx, a.DB, r.GetID())
+	if err != nil {
+		return err
+	}
+
+	var pending []persistence.Version
+	for _, v := range versions {
+		if !done[v.Tag] {
+			pending = append(pending, v)
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(
+		pending,
+		func(i, j int) bool {
+			return compareVersionTags(pending[i].Tag, pending[j].Tag) > 0
+		},
+	)
+
+	remaining := 0
+	if len(pending) > maxVersionsPerJob {
+		remaining = len(pending) - maxVersionsPerJob
+		pending = pending[:maxVersionsPerJob]
+	}
+
+	for _, v := range pending {
+		if err := a.snapshotCommit(ctx, c, r, v.Tag+" tag", v.CommitHash, run); err != nil {
+			return fmt.Errorf("unable to analyze %s tag: %w", v.Tag, err)
+		}
+
+		if err := persistence.SaveVersion(ctx, a.DB, r, v); err != nil {
+			return err
+		}
+	}
+
+	if remaining == 0 {
+		return nil
+	}
+
+	logging.Log(
+		a.Logger,
+		"[#%d %s] deferring analysis of %d remaining version(s)",
+		r.GetID(),
+		r.GetFullName(),
+		remaining,
+	)
+
+	return persistence.EnqueueJob(
+		ctx,
+		a.DB,
+		r.GetID(),
+		persistence.AnalyzeOperation,
+		persistence.BackfillTrigger,
+	)
+}
+
+// compareVersionTags compares two version tags by their semantic version.
+//
+// Tags that are not semantic versions, such as those of GitHub releases that
+// do not follow semver, are ordered before all semantic versions.
+func compareVersionTags(a, b string) int {
+	a = a[strings.LastIndexByte(a, '/')+1:]
+	b = b[strings.LastIndexByte(b, '/')+1:]
+
+	// semver.Compare() considers invalid versions to be less than all valid
+	// versions, and equal to each other.
+	return semver.Compare(a, b)
+}
+
+// listVersions returns the versions of the repository.
+func (a *Analyzer) listVersions(
+	ctx context.Context,
+	c *github.Client,
+	r *github.Repository,
+) ([]persistence.Version, error) {
+	releases := map[string]*github.RepositoryRelease{}
+	opts := &github.ListOptions{PerPage: 100}
+
+	for {
+		page, res, err := c.Repositories.ListReleases(
+			ctx,
+			r.GetOwner().GetLogin(),
+			r.GetName(),
+			opts,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rel := range page {
+			if !rel.GetDraft() {
+				releases[rel.GetTagName()] = rel
+			}
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+
+		opts.Page = res.NextPage
+	}
+
+	var versions []persistence.Version
+	opts = &github.ListOptions{PerPage: 100}
+
+	for {
+		page, res, err := c.Repositories.ListTags(
+			ctx,
+			r.GetOwner().GetLogin(),
+			r.GetName(),
+			opts,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range page {
+			rel, isRelease := releases[t.GetName()]
+
+			if isRelease || isVersionTag(t.GetName()) {
+				versions = append(versions, persistence.Version{
+					Tag:         t.GetName(),
+					CommitHash:  t.GetCommit().GetSHA(),
+					IsRelease:   isRelease,
+					ReleaseName: rel.GetName(),
+				})
+			}
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+
+		opts.Page = res.NextPage
+	}
+
+	return versions, nil
+}
@@ -18,10 +18,17 @@ func init() {
 			c *githubx.Connector,
 			l logging.Logger,
 		) (*analyzer.Analyzer, error) {
+			builds, err := analyzer.ParseBuildConfigs(analysisBuildConfigs.Value())
+			if err != nil {
+				return nil, err
+			}
+
 			return &analyzer.Analyzer{
-				DB:        db,
-				Connector: c,
-				Logger:    l,
+				DB:           db,
+				Connector:    c,
+				Logger:       l,
+				Version:      version,
+				BuildConfigs: builds,
 			}, nil
 		},
 	)
@@ -40,16 +47,40 @@ func init() {
 		},
 	)
 
-	imbue.With2(
+	imbue.With4(
 		container,
 		func(
 			ctx imbue.Context,
+			db *sql.DB,
 			a *analyzer.Analyzer,
 			r *analyzer.Remover,
+			l logging.Logger,
 		) (*analyzer.Orchestrator, error) {
 			return &analyzer.Orchestrator{
-				Analyzer: a,
-				Remover:  r,
+				DB:          db,
+				Analyzer:    a,
+				Remover:     r,
+				Logger:      l,
+				Concurrency: int(analysisWorkers.Value()),
+			}, nil
+		},
+	)
+
+	imbue.With4(
+		container,
+		func(
+			ctx imbue.Context,
+			db *sql.DB,
+			c *githubx.Connector,
+			o *analyzer.Orchestrator,
+			l logging.Logger,
+		) (*analyzer.Reconciler, error) {
+			return &analyzer.Reconciler{
+				DB:           db,
+				Connector:    c,
+				Orchestrator: o,
+				Logger:       l,
+				Interval:     reconcileInterval.Value(),
 			}, nil
 		},
 	)
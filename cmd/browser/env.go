@@ -1,6 +1,11 @@
 package main
 
-import "github.com/dogmatiq/ferrite"
+import (
+	"time"
+
+	"github.com/dogmatiq/browser/analyzer"
+	"github.com/dogmatiq/ferrite"
+)
 
 var githubAppID = ferrite.
 	Unsigned[uint]("GITHUB_APP_ID", "the ID of the GitHub application used to read repository content").
@@ -33,3 +38,27 @@ var githubURL = ferrite.
 var postgresDSN = ferrite.
 	String("DSN", "the PostgreSQL connection string").
 	Required()
+
+var analysisWorkers = ferrite.
+	Unsigned[uint]("ANALYSIS_WORKERS", "the number of repositories to analyze concurrently").
+	WithMinimum(1).
+	WithDefault(4).
+	Required()
+
+var reconcileInterval = ferrite.
+	Duration("RECONCILE_INTERVAL", "the interval at which the repositories accessible to the GitHub application are compared with those in the database").
+	WithMinimum(time.Minute).
+	WithDefault(time.Hour).
+	Required()
+
+var analysisBuildConfigs = ferrite.
+	String("ANALYSIS_BUILD_CONFIGS", "a space-separated list of build configurations (such as linux/amd64+prod) under which each repository is analyzed").
+	WithConstraint(
+		"must be a list of build configurations in GOOS/GOARCH+tag format",
+		func(v string) bool {
+			_, err := analyzer.ParseBuildConfigs(v)
+			return err == nil
+		},
+	).
+	WithDefault("default").
+	Required()
@@ -10,11 +10,9 @@ import (
 	"time"
 
 	"github.com/dogmatiq/browser/analyzer"
-	"github.com/dogmatiq/browser/githubx"
 	"github.com/dogmatiq/dodeca/logging"
 	"github.com/dogmatiq/ferrite"
 	"github.com/dogmatiq/imbue"
-	"github.com/google/go-github/v38/github"
 )
 
 var (
@@ -65,31 +63,13 @@ func main() {
 		},
 	)
 
-	imbue.Go2(
+	imbue.Go1(
 		g,
 		func(
 			ctx context.Context,
-			c *githubx.Connector,
-			o *analyzer.Orchestrator,
+			r *analyzer.Reconciler,
 		) error {
-			return githubx.ListInstallations(
-				ctx,
-				c.AppClient,
-				func(ctx context.Context, i *github.Installation) error {
-					ic, err := c.InstallationClient(ctx, i.GetID())
-					if err != nil {
-						return err
-					}
-
-					return githubx.ListRepos(
-						ctx,
-						ic,
-						func(ctx context.Context, r *github.Repository) error {
-							return o.EnqueueAnalyis(ctx, r.GetID())
-						},
-					)
-				},
-			)
+			return r.Run(ctx)
 		},
 	)
 
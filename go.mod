@@ -16,8 +16,10 @@ require (
 	github.com/jackc/pgx/v4 v4.18.3
 	golang.org/x/mod v0.23.0
 	golang.org/x/oauth2 v0.26.0
+	golang.org/x/sync v0.11.0
 	golang.org/x/tools v0.30.0
 	gopkg.in/square/go-jose.v2 v2.6.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -61,9 +63,7 @@ require (
 	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/exp v0.0.0-20240719175910-8a7402abbf56 // indirect
 	golang.org/x/net v0.35.0 // indirect
-	golang.org/x/sync v0.11.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
 	google.golang.org/protobuf v1.34.2 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -6,14 +6,59 @@ import (
 	"fmt"
 
 	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/configkit/message"
 	"github.com/google/go-github/v38/github"
 )
 
+// Application is a Dogma application discovered within a repository.
+type Application struct {
+	Config configkit.Application
+
+	// Module is the path of the Go module that contains the application.
+	Module string
+
+	// BuildConfigs is the names of the build configurations under which the
+	// application was discovered.
+	BuildConfigs []string
+
+	// Handlers is the handlers within the application, discovered under any of
+	// the build configurations.
+	Handlers []Handler
+}
+
+// Handler is a message handler within an Application.
+type Handler struct {
+	Config configkit.Handler
+
+	// BuildConfigs is the names of the build configurations under which the
+	// handler was discovered.
+	BuildConfigs []string
+
+	// Messages is the messages used by the handler, discovered under any of
+	// the build configurations. It is used in place of Config.MessageNames(),
+	// which only describes the handler under a single build configuration.
+	Messages []HandlerMessage
+}
+
+// HandlerMessage is a message that is produced or consumed by a Handler.
+type HandlerMessage struct {
+	// Name is the fully-qualified name of the message type.
+	Name string
+
+	Kind       message.Kind
+	IsProduced bool
+	IsConsumed bool
+
+	// BuildConfigs is the names of the build configurations under which the
+	// handler uses the message.
+	BuildConfigs []string
+}
+
 func syncApplications(
 	ctx context.Context,
 	tx *sql.Tx,
 	r *github.Repository,
-	apps []configkit.Application,
+	apps []Application,
 ) error {
 	if _, err := tx.ExecContext(
 		ctx,
@@ -48,61 +93,85 @@ func syncApplication(
 	ctx context.Context,
 	tx *sql.Tx,
 	r *github.Repository,
-	a configkit.Application,
+	a Application,
 ) error {
-	typeID, isPointer, err := syncTypeRef(ctx, tx, a.TypeName())
+	typeID, isPointer, err := syncTypeRef(ctx, tx, a.Config.TypeName())
 	if err != nil {
 		return err
 	}
 
-	if _, err := tx.ExecContext(
+	// An application that uses a key that is already in use by an application
+	// in another repository is not stored. It is recorded as a declaration
+	// instead, such that the collision is reported.
+	res, err := tx.ExecContext(
 		ctx,
-		`INSERT INTO dogmabrowser.application (
+		`INSERT INTO dogmabrowser.application AS a (
 			key,
 			name,
 			type_id,
 			is_pointer,
-			repository_id
+			repository_id,
+			module_path
 		) VALUES (
-			$1, $2, $3, $4, $5
+			$1, $2, $3, $4, $5, $6
 		) ON CONFLICT (key) DO UPDATE SET
 			name = excluded.name,
 			type_id = excluded.type_id,
 			is_pointer = excluded.is_pointer,
-			repository_id = excluded.repository_id,
-			needs_removal = FALSE`,
-		a.Identity().Key,
-		a.Identity().Name,
+			module_path = excluded.module_path,
+			needs_removal = FALSE
+		WHERE a.repository_id = excluded.repository_id`,
+		a.Config.Identity().Key,
+		a.Config.Identity().Name,
 		typeID,
 		isPointer,
 		r.GetID(),
-	); err != nil {
+		a.Module,
+	)
+	if err != nil {
 		return fmt.Errorf("unable to sync application: %w", err)
 	}
 
-	return syncHandlers(ctx, tx, a)
+	if n, err := res.RowsAffected(); n == 0 || err != nil {
+		return err
+	}
+
+	if err := syncBuildConfigs(
+		ctx,
+		tx,
+		"application_build",
+		"application_key",
+		a.Config.Identity().Key,
+		a.BuildConfigs,
+	); err != nil {
+		return err
+	}
+
+	return syncHandlers(ctx, tx, r, a)
 }
 
 func syncHandlers(
 	ctx context.Context,
 	tx *sql.Tx,
-	a configkit.Application,
+	r *github.Repository,
+	a Application,
 ) error {
 	if _, err := tx.ExecContext(
 		ctx,
 		`UPDATE dogmabrowser.handler SET
 			needs_removal = TRUE
 		WHERE application_key = $1`,
-		a.Identity().Key,
+		a.Config.Identity().Key,
 	); err != nil {
 		return fmt.Errorf("unable to mark handlers for removal: %w", err)
 	}
 
-	for _, h := range a.Handlers() {
+	for _, h := range a.Handlers {
 		if err := syncHandler(
 			ctx,
 			tx,
-			a.Identity().Key,
+			r,
+			a.Config.Identity().Key,
 			h,
 		); err != nil {
 			return err
@@ -114,7 +183,7 @@ func syncHandlers(
 		`DELETE FROM dogmabrowser.handler
 		WHERE application_key = $1
 		AND needs_removal`,
-		a.Identity().Key,
+		a.Config.Identity().Key,
 	); err != nil {
 		return fmt.Errorf("unable to remove handlers: %w", err)
 	}
@@ -125,17 +194,21 @@ func syncHandlers(
 func syncHandler(
 	ctx context.Context,
 	tx *sql.Tx,
+	r *github.Repository,
 	appKey string,
-	h configkit.Handler,
+	h Handler,
 ) error {
-	typeID, isPointer, err := syncTypeRef(ctx, tx, h.TypeName())
+	typeID, isPointer, err := syncTypeRef(ctx, tx, h.Config.TypeName())
 	if err != nil {
 		return err
 	}
 
-	if _, err := tx.ExecContext(
+	// A handler may move between applications within the same repository, but
+	// a handler that uses a key that is already in use by a handler in another
+	// repository is not stored.
+	res, err := tx.ExecContext(
 		ctx,
-		`INSERT INTO dogmabrowser.handler (
+		`INSERT INTO dogmabrowser.handler AS h (
 			key,
 			name,
 			application_key,
@@ -150,37 +223,112 @@ func syncHandler(
 			handler_type = excluded.handler_type,
 			type_id = excluded.type_id,
 			is_pointer = excluded.is_pointer,
-			needs_removal = FALSE`,
-		h.Identity().Key,
-		h.Identity().Name,
+			needs_removal = FALSE
+		WHERE EXISTS (
+			SELECT *
+			FROM dogmabrowser.application AS a
+			WHERE a.key = h.application_key
+			AND a.repository_id = $7
+		)`,
+		h.Config.Identity().Key,
+		h.Config.Identity().Name,
 		appKey,
-		h.HandlerType(),
+		h.Config.HandlerType(),
 		typeID,
 		isPointer,
-	); err != nil {
+		r.GetID(),
+	)
+	if err != nil {
 		return fmt.Errorf("unable to sync handler: %w", err)
 	}
 
+	if n, err := res.RowsAffected(); n == 0 || err != nil {
+		return err
+	}
+
+	if err := syncBuildConfigs(
+		ctx,
+		tx,
+		"handler_build",
+		"handler_key",
+		h.Config.Identity().Key,
+		h.BuildConfigs,
+	); err != nil {
+		return err
+	}
+
 	return syncMessages(ctx, tx, h)
 }
 
+// syncBuildConfigs replaces the build configurations associated with an
+// application or handler.
+//
+// table and column are the name of the table that associates build
+// configurations with the entity, and the name of the column that contains the
+// entity's key, respectively.
+func syncBuildConfigs(
+	ctx context.Context,
+	tx *sql.Tx,
+	table, column string,
+	key string,
+	builds []string,
+) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.`+table+`
+		WHERE `+column+` = $1`,
+		key,
+	); err != nil {
+		return fmt.Errorf("unable to remove build configurations: %w", err)
+	}
+
+	for _, b := range builds {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO dogmabrowser.`+table+` (
+				`+column+`,
+				build_config
+			) VALUES (
+				$1, $2
+			) ON CONFLICT DO NOTHING`,
+			key,
+			b,
+		); err != nil {
+			return fmt.Errorf("unable to sync build configuration: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func syncMessages(
 	ctx context.Context,
 	tx *sql.Tx,
-	h configkit.Handler,
+	h Handler,
 ) error {
+	key := h.Config.Identity().Key
+
 	if _, err := tx.ExecContext(
 		ctx,
 		`UPDATE dogmabrowser.handler_message SET
 			needs_removal = TRUE
 		WHERE handler_key = $1`,
-		h.Identity().Key,
+		key,
 	); err != nil {
 		return fmt.Errorf("unable to mark messages for removal: %w", err)
 	}
 
-	for n, em := range h.MessageNames() {
-		typeID, isPointer, err := syncTypeRef(ctx, tx, n.String())
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.handler_message_build
+		WHERE handler_key = $1`,
+		key,
+	); err != nil {
+		return fmt.Errorf("unable to remove message build configurations: %w", err)
+	}
+
+	for _, m := range h.Messages {
+		typeID, isPointer, err := syncTypeRef(ctx, tx, m.Name)
 		if err != nil {
 			return err
 		}
@@ -201,15 +349,35 @@ func syncMessages(
 				is_produced = excluded.is_produced,
 				is_consumed = excluded.is_consumed,
 				needs_removal = FALSE`,
-			h.Identity().Key,
+			key,
 			typeID,
 			isPointer,
-			em.Kind,
-			em.IsProduced,
-			em.IsConsumed,
+			m.Kind,
+			m.IsProduced,
+			m.IsConsumed,
 		); err != nil {
 			return fmt.Errorf("unable to sync message: %w", err)
 		}
+
+		for _, b := range m.BuildConfigs {
+			if _, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO dogmabrowser.handler_message_build (
+					handler_key,
+					type_id,
+					is_pointer,
+					build_config
+				) VALUES (
+					$1, $2, $3, $4
+				) ON CONFLICT DO NOTHING`,
+				key,
+				typeID,
+				isPointer,
+				b,
+			); err != nil {
+				return fmt.Errorf("unable to sync message build configuration: %w", err)
+			}
+		}
 	}
 
 	if _, err := tx.ExecContext(
@@ -217,7 +385,7 @@ func syncMessages(
 		`DELETE FROM dogmabrowser.handler_message
 		WHERE handler_key = $1
 		AND needs_removal`,
-		h.Identity().Key,
+		key,
 	); err != nil {
 		return fmt.Errorf("unable to remove messages: %w", err)
 	}
@@ -0,0 +1,699 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeEntity is an enumeration of the parts of a repository's Dogma topology
+// that may change between commits.
+type ChangeEntity string
+
+const (
+	// ApplicationChange is a change to an application.
+	ApplicationChange ChangeEntity = "application"
+
+	// HandlerChange is a change to a handler.
+	HandlerChange ChangeEntity = "handler"
+
+	// MessageRoleChange is a change to the way a handler uses a message.
+	MessageRoleChange ChangeEntity = "message-role"
+
+	// MessageFieldChange is a change to a field of a message struct type.
+	MessageFieldChange ChangeEntity = "message-field"
+)
+
+// ChangeAction is an enumeration of the ways in which an entity may change.
+type ChangeAction string
+
+const (
+	// AddedAction indicates that the entity is present in the newer commit
+	// but not the older one.
+	AddedAction ChangeAction = "added"
+
+	// RemovedAction indicates that the entity is present in the older commit
+	// but not the newer one.
+	RemovedAction ChangeAction = "removed"
+
+	// ChangedAction indicates that the entity is present in both commits, but
+	// its details differ.
+	ChangedAction ChangeAction = "changed"
+
+	// RenamedAction indicates that a message field that is present in the
+	// older commit has been replaced by a field with a different name, but
+	// the same type and struct tag, in the newer commit.
+	RenamedAction ChangeAction = "renamed"
+)
+
+// Change is a single change to the Dogma topology of a repository.
+type Change struct {
+	Entity ChangeEntity
+	Action ChangeAction
+
+	// Key and Name are the identity of the application or handler that
+	// changed. For message roles, they are the identity of the handler. For
+	// message fields, Key is empty and Name is the name of the field.
+	Key  string
+	Name string
+
+	// MessageType is the fully-qualified name of the message type, without
+	// any pointer prefix. It is empty for application and handler changes.
+	MessageType string
+
+	// Before and After are human-readable descriptions of the entity in the
+	// older and newer commits, respectively. Before is empty for additions,
+	// and After is empty for removals.
+	Before string
+	After  string
+}
+
+// CompareSnapshots returns the changes to the Dogma topology of a repository
+// between the snapshots of two commits.
+//
+// Both commits must already have been snapshotted.
+func CompareSnapshots(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	before, after string,
+) ([]Change, error) {
+	b, err := loadTopology(ctx, db, repoID, before)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := loadTopology(ctx, db, repoID, after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+
+	changes = compareEntities(changes, ApplicationChange, b.apps, a.apps)
+	changes = compareEntities(changes, HandlerChange, b.handlers, a.handlers)
+	changes = compareEntities(changes, MessageRoleChange, b.roles, a.roles)
+
+	// Message shapes are only compared if both snapshots recorded them, as
+	// snapshots taken by earlier versions of the browser did not.
+	if b.hasMessageShapes && a.hasMessageShapes {
+		changes = compareFields(changes, b, a)
+	}
+
+	return changes, nil
+}
+
+// RecordChangeSet stores the changes to the Dogma topology of a repository
+// between two commits of its default branch.
+//
+// It does nothing if either commit has not been snapshotted, which is the case
+// for commits that were analyzed before snapshots were introduced.
+func RecordChangeSet(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	before, after string,
+) error {
+	if before == "" || before == after {
+		return nil
+	}
+
+	for _, commit := range []string{before, after} {
+		ok, err := SnapshotExists(ctx, db, repoID, commit)
+		if !ok || err != nil {
+			return err
+		}
+	}
+
+	changes, err := CompareSnapshots(ctx, db, repoID, before, after)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	// Remove any change set that was recorded for the same commit by an
+	// earlier analysis, such as one that was later marked as stale.
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.change
+		WHERE repository_id = $1
+		AND commit_hash = $2`,
+		repoID,
+		after,
+	); err != nil {
+		return fmt.Errorf("unable to remove change set: %w", err)
+	}
+
+	for _, c := range changes {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO dogmabrowser.change (
+				repository_id,
+				commit_hash,
+				previous_commit_hash,
+				entity,
+				action,
+				key,
+				name,
+				message_type,
+				before_value,
+				after_value
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+			)`,
+			repoID,
+			after,
+			before,
+			c.Entity,
+			c.Action,
+			c.Key,
+			c.Name,
+			c.MessageType,
+			c.Before,
+			c.After,
+		); err != nil {
+			return fmt.Errorf("unable to record change: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AnalyzedCommit returns the hash of the commit of the repository's default
+// branch that was most recently analyzed.
+//
+// It returns false if the repository has not been analyzed.
+func AnalyzedCommit(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+) (string, bool, error) {
+	row := db.QueryRowContext(
+		ctx,
+		`SELECT commit_hash
+		FROM dogmabrowser.repository
+		WHERE id = $1`,
+		repoID,
+	)
+
+	var commit string
+	if err := row.Scan(&commit); err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+
+		return "", false, fmt.Errorf("unable to load analyzed commit: %w", err)
+	}
+
+	return commit, true, nil
+}
+
+// topologyEntity is an application, handler or message role within a
+// topology.
+type topologyEntity struct {
+	Key         string
+	Name        string
+	MessageType string
+
+	// Description is a human-readable description of the entity's details. The
+	// entity has changed if its description differs between commits.
+	Description string
+
+	// Signature is the type and struct tag of a message field, which is used
+	// to detect fields that have been renamed.
+	Signature string
+}
+
+// topology is the set of applications, handlers and message roles within a
+// snapshot, keyed by a value that identifies each entity across commits.
+type topology struct {
+	apps     map[string]topologyEntity
+	handlers map[string]topologyEntity
+	roles    map[string]topologyEntity
+
+	// hasMessageShapes is true if the snapshot recorded the fields of the
+	// message types defined within the commit, in which case messages is the
+	// set of those types and fields is their fields.
+	hasMessageShapes bool
+	messages         map[string]struct{}
+	fields           map[string]topologyEntity
+}
+
+// loadTopology loads the topology within the snapshot of the given commit.
+func loadTopology(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	commit string,
+) (topology, error) {
+	t := topology{
+		apps:     map[string]topologyEntity{},
+		handlers: map[string]topologyEntity{},
+		roles:    map[string]topologyEntity{},
+		messages: map[string]struct{}{},
+		fields:   map[string]topologyEntity{},
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT
+			a.key,
+			a.name,
+			a.type_package,
+			a.type_name,
+			a.is_pointer
+		FROM dogmabrowser.snapshot_application AS a
+		WHERE a.repository_id = $1
+		AND a.commit_hash = $2`,
+		repoID,
+		commit,
+	)
+	if err != nil {
+		return topology{}, fmt.Errorf("unable to query applications: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			e         topologyEntity
+			pkg, name string
+			isPointer bool
+		)
+
+		if err := rows.Scan(&e.Key, &e.Name, &pkg, &name, &isPointer); err != nil {
+			return topology{}, fmt.Errorf("unable to scan application: %w", err)
+		}
+
+		e.Description = fmt.Sprintf(
+			"%s implemented by %s",
+			e.Name,
+			formatTypeName(pkg, name, isPointer),
+		)
+
+		t.apps[e.Key] = e
+	}
+
+	if err := rows.Err(); err != nil {
+		return topology{}, err
+	}
+
+	rows, err = db.QueryContext(
+		ctx,
+		`SELECT
+			h.key,
+			h.name,
+			h.handler_type,
+			h.type_package,
+			h.type_name,
+			h.is_pointer,
+			COALESCE(a.name, h.application_key)
+		FROM dogmabrowser.snapshot_handler AS h
+		LEFT JOIN dogmabrowser.snapshot_application AS a
+		ON a.repository_id = h.repository_id
+		AND a.commit_hash = h.commit_hash
+		AND a.key = h.application_key
+		WHERE h.repository_id = $1
+		AND h.commit_hash = $2`,
+		repoID,
+		commit,
+	)
+	if err != nil {
+		return topology{}, fmt.Errorf("unable to query handlers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			e                      topologyEntity
+			handlerType, pkg, name string
+			isPointer              bool
+			appName                string
+		)
+
+		if err := rows.Scan(
+			&e.Key,
+			&e.Name,
+			&handlerType,
+			&pkg,
+			&name,
+			&isPointer,
+			&appName,
+		); err != nil {
+			return topology{}, fmt.Errorf("unable to scan handler: %w", err)
+		}
+
+		e.Description = fmt.Sprintf(
+			"%s %s implemented by %s, within the %s application",
+			e.Name,
+			handlerType,
+			formatTypeName(pkg, name, isPointer),
+			appName,
+		)
+
+		t.handlers[e.Key] = e
+	}
+
+	if err := rows.Err(); err != nil {
+		return topology{}, err
+	}
+
+	rows, err = db.QueryContext(
+		ctx,
+		`SELECT
+			h.key,
+			h.name,
+			m.type_package,
+			m.type_name,
+			m.is_pointer,
+			m.kind,
+			m.is_produced,
+			m.is_consumed
+		FROM dogmabrowser.snapshot_handler_message AS m
+		INNER JOIN dogmabrowser.snapshot_handler AS h
+		ON h.repository_id = m.repository_id
+		AND h.commit_hash = m.commit_hash
+		AND h.key = m.handler_key
+		WHERE m.repository_id = $1
+		AND m.commit_hash = $2
+		ORDER BY m.is_pointer`,
+		repoID,
+		commit,
+	)
+	if err != nil {
+		return topology{}, fmt.Errorf("unable to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			e                      topologyEntity
+			pkg, name              string
+			isPointer              bool
+			kindText               string
+			isProduced, isConsumed bool
+		)
+
+		if err := rows.Scan(
+			&e.Key,
+			&e.Name,
+			&pkg,
+			&name,
+			&isPointer,
+			&kindText,
+			&isProduced,
+			&isConsumed,
+		); err != nil {
+			return topology{}, fmt.Errorf("unable to scan message: %w", err)
+		}
+
+		kind, err := ParseKind(kindText)
+		if err != nil {
+			return topology{}, err
+		}
+
+		e.MessageType = pkg + "." + name
+
+		var verbs []string
+		if isProduced {
+			verbs = append(verbs, "produces")
+		}
+		if isConsumed {
+			verbs = append(verbs, "consumes")
+		}
+
+		desc := fmt.Sprintf(
+			"%s %s as %s",
+			strings.Join(verbs, " and "),
+			formatTypeName(pkg, name, isPointer),
+			kind,
+		)
+
+		// A handler may use both the pointer and non-pointer forms of the same
+		// message, in which case they are described as a single role.
+		k := e.Key + " " + e.MessageType
+		if x, ok := t.roles[k]; ok {
+			desc = x.Description + "; " + desc
+		}
+
+		e.Description = desc
+		t.roles[k] = e
+	}
+
+	if err := rows.Err(); err != nil {
+		return topology{}, err
+	}
+
+	if err := loadMessageShapes(ctx, db, repoID, commit, &t); err != nil {
+		return topology{}, err
+	}
+
+	return t, nil
+}
+
+// loadMessageShapes loads the fields of the message types within the snapshot
+// of the given commit into t.
+func loadMessageShapes(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	commit string,
+	t *topology,
+) error {
+	row := db.QueryRowContext(
+		ctx,
+		`SELECT has_message_shapes
+		FROM dogmabrowser.snapshot
+		WHERE repository_id = $1
+		AND commit_hash = $2`,
+		repoID,
+		commit,
+	)
+
+	if err := row.Scan(&t.hasMessageShapes); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+
+		return fmt.Errorf("unable to load snapshot: %w", err)
+	}
+
+	if !t.hasMessageShapes {
+		return nil
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT
+			m.type_package,
+			m.type_name,
+			COALESCE(f.name, ''),
+			COALESCE(f.field_type, ''),
+			COALESCE(f.tag, '')
+		FROM dogmabrowser.snapshot_message_type AS m
+		LEFT JOIN dogmabrowser.snapshot_message_field AS f
+		ON f.repository_id = m.repository_id
+		AND f.commit_hash = m.commit_hash
+		AND f.type_package = m.type_package
+		AND f.type_name = m.type_name
+		WHERE m.repository_id = $1
+		AND m.commit_hash = $2
+		ORDER BY f.position`,
+		repoID,
+		commit,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to query message fields: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			e                   topologyEntity
+			pkg, name           string
+			fieldType, fieldTag string
+		)
+
+		if err := rows.Scan(
+			&pkg,
+			&name,
+			&e.Name,
+			&fieldType,
+			&fieldTag,
+		); err != nil {
+			return fmt.Errorf("unable to scan message field: %w", err)
+		}
+
+		e.MessageType = pkg + "." + name
+		t.messages[e.MessageType] = struct{}{}
+
+		// A message type without any fields is still recorded, such that
+		// fields added to it later are detected.
+		if e.Name == "" {
+			continue
+		}
+
+		e.Signature = fieldType
+		if fieldTag != "" {
+			e.Signature += " `" + fieldTag + "`"
+		}
+
+		e.Description = e.Name + " " + e.Signature
+		t.fields[e.MessageType+" "+e.Name] = e
+	}
+
+	return rows.Err()
+}
+
+// compareFields appends a change to changes for each field that differs
+// between the message types in before and after, and returns the updated
+// slice.
+//
+// Only message types that are present in both commits are compared. A field
+// that is removed from a message in the same commit that a field with the same
+// type and struct tag is added is assumed to have been renamed.
+func compareFields(changes []Change, before, after topology) []Change {
+	b := map[string]topologyEntity{}
+	for k, e := range before.fields {
+		if _, ok := after.messages[e.MessageType]; ok {
+			b[k] = e
+		}
+	}
+
+	a := map[string]topologyEntity{}
+	for k, e := range after.fields {
+		if _, ok := before.messages[e.MessageType]; ok {
+			a[k] = e
+		}
+	}
+
+	var fields []Change
+	fields = compareEntities(fields, MessageFieldChange, b, a)
+
+	// Fields are paired in order of their message type and name, such that
+	// when several removed fields share a signature they are paired with the
+	// added fields in the same way each time the commits are compared.
+	sort.SliceStable(
+		fields,
+		func(i, j int) bool {
+			if fields[i].MessageType != fields[j].MessageType {
+				return fields[i].MessageType < fields[j].MessageType
+			}
+			return fields[i].Name < fields[j].Name
+		},
+	)
+
+	for i, r := range fields {
+		if r.Action != RemovedAction {
+			continue
+		}
+
+		removed := b[r.MessageType+" "+r.Name]
+
+		for j, x := range fields {
+			if x.Action != AddedAction || x.MessageType != r.MessageType {
+				continue
+			}
+
+			if a[x.MessageType+" "+x.Name].Signature != removed.Signature {
+				continue
+			}
+
+			fields[i] = Change{
+				Entity:      MessageFieldChange,
+				Action:      RenamedAction,
+				Name:        x.Name,
+				MessageType: x.MessageType,
+				Before:      r.Before,
+				After:       x.After,
+			}
+
+			// The added field is consumed by the rename, and is removed from
+			// the result below.
+			fields[j].Action = ""
+			break
+		}
+	}
+
+	for _, c := range fields {
+		if c.Action != "" {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes
+}
+
+// compareEntities appends a change to changes for each entity that differs
+// between before and after, and returns the updated slice.
+func compareEntities(
+	changes []Change,
+	entity ChangeEntity,
+	before, after map[string]topologyEntity,
+) []Change {
+	var keys []string
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		b, inBefore := before[k]
+		a, inAfter := after[k]
+
+		switch {
+		case !inBefore:
+			changes = append(changes, Change{
+				Entity:      entity,
+				Action:      AddedAction,
+				Key:         a.Key,
+				Name:        a.Name,
+				MessageType: a.MessageType,
+				After:       a.Description,
+			})
+		case !inAfter:
+			changes = append(changes, Change{
+				Entity:      entity,
+				Action:      RemovedAction,
+				Key:         b.Key,
+				Name:        b.Name,
+				MessageType: b.MessageType,
+				Before:      b.Description,
+			})
+		case a.Description != b.Description:
+			changes = append(changes, Change{
+				Entity:      entity,
+				Action:      ChangedAction,
+				Key:         a.Key,
+				Name:        a.Name,
+				MessageType: a.MessageType,
+				Before:      b.Description,
+				After:       a.Description,
+			})
+		}
+	}
+
+	return changes
+}
+
+// formatTypeName returns the fully-qualified name of a Go type.
+func formatTypeName(pkg, name string, isPointer bool) string {
+	n := pkg + "." + name
+	if isPointer {
+		return "*" + n
+	}
+
+	return n
+}
@@ -0,0 +1,85 @@
+package persistence
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareFields(t *testing.T) {
+	field := func(name, signature string) topologyEntity {
+		return topologyEntity{
+			Name:        name,
+			MessageType: "pkg.Message",
+			Description: name + " " + signature,
+			Signature:   signature,
+		}
+	}
+
+	shape := func(fields ...topologyEntity) topology {
+		t := topology{
+			messages: map[string]struct{}{"pkg.Message": {}},
+			fields:   map[string]topologyEntity{},
+		}
+		for _, f := range fields {
+			t.fields[f.MessageType+" "+f.Name] = f
+		}
+		return t
+	}
+
+	before := shape(
+		field("Amount", "int64"),
+		field("B", "string"),
+		field("A", "string"),
+	)
+
+	after := shape(
+		field("Amount", "uint64"),
+		field("Y", "string"),
+		field("X", "string"),
+		field("Z", "bool"),
+	)
+
+	want := []Change{
+		{
+			Entity:      MessageFieldChange,
+			Action:      RenamedAction,
+			Name:        "X",
+			MessageType: "pkg.Message",
+			Before:      "A string",
+			After:       "X string",
+		},
+		{
+			Entity:      MessageFieldChange,
+			Action:      ChangedAction,
+			Name:        "Amount",
+			MessageType: "pkg.Message",
+			Before:      "Amount int64",
+			After:       "Amount uint64",
+		},
+		{
+			Entity:      MessageFieldChange,
+			Action:      RenamedAction,
+			Name:        "Y",
+			MessageType: "pkg.Message",
+			Before:      "B string",
+			After:       "Y string",
+		},
+		{
+			Entity:      MessageFieldChange,
+			Action:      AddedAction,
+			Name:        "Z",
+			MessageType: "pkg.Message",
+			After:       "Z bool",
+		},
+	}
+
+	// Run the comparison several times, as the pairing of renamed fields must
+	// not depend on map iteration order.
+	for i := 0; i < 20; i++ {
+		got := compareFields(nil, before, after)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected changes:\n got: %#v\nwant: %#v", got, want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dogmatiq/configkit"
+	"github.com/google/go-github/v38/github"
+)
+
+// Declaration is an application as it was discovered within a single module
+// under a single build configuration, before it is merged with any other
+// applications that have the same identity key.
+type Declaration struct {
+	Config configkit.Application
+
+	// Module is the path of the Go module that contains the application.
+	Module string
+}
+
+// The entities that declare an identity key.
+const (
+	applicationDeclaration = "application"
+	handlerDeclaration     = "handler"
+)
+
+// syncDeclarations replaces the record of the identity keys declared by the
+// repository's applications and handlers.
+//
+// Declarations are recorded even when another application or handler already
+// declares the same key, whether in this repository or another, such that key
+// collisions can be detected and reported, and so that the declaration can take
+// the place of the other when it is removed.
+func syncDeclarations(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+	decls []Declaration,
+) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.identity_declaration
+		WHERE repository_id = $1`,
+		r.GetID(),
+	); err != nil {
+		return fmt.Errorf("unable to remove identity declarations: %w", err)
+	}
+
+	for _, d := range decls {
+		if err := syncDeclaration(
+			ctx,
+			tx,
+			r,
+			applicationDeclaration,
+			d.Config.Identity().Key,
+			d.Config.Identity().Name,
+			d,
+			d.Config.TypeName(),
+		); err != nil {
+			return err
+		}
+
+		for _, h := range d.Config.Handlers() {
+			if err := syncDeclaration(
+				ctx,
+				tx,
+				r,
+				handlerDeclaration,
+				h.Identity().Key,
+				h.Identity().Name,
+				d,
+				h.TypeName(),
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// syncDeclaration records the declaration of a single identity key by an
+// application, or by a handler within that application.
+func syncDeclaration(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+	entity, key, name string,
+	d Declaration,
+	typeName string,
+) error {
+	pkg, n, _ := parseTypeName(typeName)
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.identity_declaration (
+			repository_id,
+			entity,
+			key,
+			name,
+			application_key,
+			application_name,
+			type_package,
+			type_name,
+			module_path
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		) ON CONFLICT DO NOTHING`,
+		r.GetID(),
+		entity,
+		key,
+		name,
+		d.Config.Identity().Key,
+		d.Config.Identity().Name,
+		pkg,
+		n,
+		d.Module,
+	); err != nil {
+		return fmt.Errorf("unable to sync identity declaration: %w", err)
+	}
+
+	return nil
+}
+
+// detectCollisions records a finding for each identity key that is declared by
+// the repository and also declared by a different application or handler,
+// either in another repository or within the same repository, unless the
+// collision has already been recorded.
+func detectCollisions(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+	commit string,
+) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.finding (
+			repository_id,
+			commit_hash,
+			affected_repository_id,
+			affected_application,
+			category,
+			type_package,
+			type_name,
+			identity_key
+		)
+		SELECT DISTINCT
+			$1,
+			$2,
+			x.repository_id,
+			x.application_name,
+			CASE WHEN d.entity = $3 THEN $4 ELSE $5 END,
+			d.type_package,
+			d.type_name,
+			d.key
+		FROM dogmabrowser.identity_declaration AS d
+		INNER JOIN dogmabrowser.identity_declaration AS x
+		ON x.entity = d.entity
+		AND x.key = d.key
+		AND (x.repository_id, x.application_key, x.type_package, x.type_name)
+			!= (d.repository_id, d.application_key, d.type_package, d.type_name)
+		WHERE d.repository_id = $1
+		-- A collision between two declarations within the repository is
+		-- recorded once, rather than once for each of the declarations.
+		AND (
+			x.repository_id != d.repository_id
+			OR (x.application_key, x.type_package, x.type_name)
+				< (d.application_key, d.type_package, d.type_name)
+		)
+		AND NOT EXISTS (
+			SELECT *
+			FROM dogmabrowser.finding AS f
+			WHERE f.identity_key = d.key
+			AND f.category = CASE WHEN d.entity = $3 THEN $4 ELSE $5 END
+			AND (
+				(f.repository_id = $1 AND f.affected_repository_id = x.repository_id)
+				OR (f.repository_id = x.repository_id AND f.affected_repository_id = $1)
+			)
+		)`,
+		r.GetID(),
+		commit,
+		applicationDeclaration,
+		ApplicationKeyCollisionFinding,
+		HandlerKeyCollisionFinding,
+	); err != nil {
+		return fmt.Errorf("unable to detect identity key collisions: %w", err)
+	}
+
+	return nil
+}
+
+// markOrphanedDeclarationsStale marks each repository other than the given
+// repository as stale if it declares an application or handler that could not
+// previously be stored because its key was already in use, but which is no
+// longer in use.
+//
+// This allows the declaration to take the place of one that has been removed
+// when the repository is next analyzed.
+func markOrphanedDeclarationsStale(
+	ctx context.Context,
+	tx *sql.Tx,
+	repoID int64,
+) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.repository AS r SET
+			is_stale = TRUE,
+			stale_since = NOW()
+		WHERE r.id != $1
+		AND r.is_stale = FALSE
+		AND EXISTS (
+			SELECT *
+			FROM dogmabrowser.identity_declaration AS d
+			WHERE d.repository_id = r.id
+			AND (
+				(
+					d.entity = $2
+					AND NOT EXISTS (
+						SELECT *
+						FROM dogmabrowser.application AS a
+						WHERE a.key = d.key
+					)
+				) OR (
+					d.entity = $3
+					AND NOT EXISTS (
+						SELECT *
+						FROM dogmabrowser.handler AS h
+						WHERE h.key = d.key
+					)
+					AND EXISTS (
+						SELECT *
+						FROM dogmabrowser.application AS a
+						WHERE a.key = d.application_key
+						AND a.repository_id = d.repository_id
+					)
+				)
+			)
+		)`,
+		repoID,
+		applicationDeclaration,
+		handlerDeclaration,
+	); err != nil {
+		return fmt.Errorf("unable to mark repositories with orphaned declarations as stale: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DeliveryOutcome is an enumeration of the possible outcomes of processing a
+// GitHub webhook delivery.
+type DeliveryOutcome string
+
+const (
+	// PendingDelivery indicates that the delivery has been received but has
+	// not yet been processed.
+	PendingDelivery DeliveryOutcome = "pending"
+
+	// ProcessedDelivery indicates that the delivery was processed
+	// successfully.
+	ProcessedDelivery DeliveryOutcome = "processed"
+
+	// FailedDelivery indicates that processing the delivery failed with an
+	// error.
+	FailedDelivery DeliveryOutcome = "failed"
+)
+
+// maxDeliveries is the number of webhook deliveries retained. Older deliveries
+// are deleted when a new delivery is recorded.
+const maxDeliveries = 1000
+
+// Delivery is a record of a single GitHub webhook delivery.
+type Delivery struct {
+	// ID is the GUID that GitHub assigns to the delivery, as given by the
+	// X-GitHub-Delivery header. It is the same for each redelivery.
+	ID string
+
+	// Event is the type of the event, as given by the X-GitHub-Event header,
+	// and Action is the "action" field of the payload, if any.
+	Event  string
+	Action string
+
+	// RepositoryID and FullName identify the repository that the event
+	// relates to. They are zero for events that do not relate to a single
+	// repository, such as installation events.
+	RepositoryID int64
+	FullName     string
+
+	Payload []byte
+}
+
+// RecordDelivery stores a record of a webhook delivery.
+//
+// It returns false if a delivery with the same ID has already been processed
+// successfully, in which case it must not be processed again. Otherwise, the
+// caller must process the delivery and then call CompleteDelivery().
+func RecordDelivery(
+	ctx context.Context,
+	db *sql.DB,
+	d Delivery,
+) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	row := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO dogmabrowser.delivery AS d (
+			id,
+			event,
+			action,
+			repository_id,
+			full_name,
+			payload,
+			outcome
+		) VALUES (
+			$1, $2, $3, NULLIF($4, 0), $5, $6, $7
+		) ON CONFLICT (id) DO UPDATE SET
+			receipts = d.receipts + 1
+		RETURNING d.outcome`,
+		d.ID,
+		d.Event,
+		d.Action,
+		d.RepositoryID,
+		d.FullName,
+		d.Payload,
+		PendingDelivery,
+	)
+
+	var outcome DeliveryOutcome
+	if err := row.Scan(&outcome); err != nil {
+		return false, fmt.Errorf("unable to record delivery: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.delivery
+		WHERE received_at < (
+			SELECT MIN(received_at) FROM (
+				SELECT received_at
+				FROM dogmabrowser.delivery
+				ORDER BY received_at DESC
+				LIMIT $1
+			) AS x
+		)`,
+		maxDeliveries,
+	); err != nil {
+		return false, fmt.Errorf("unable to prune deliveries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return outcome != ProcessedDelivery, nil
+}
+
+// CompleteDelivery records the result of processing a webhook delivery.
+//
+// cause is the error that caused processing to fail, or nil if the delivery
+// was processed successfully.
+func CompleteDelivery(
+	ctx context.Context,
+	db *sql.DB,
+	id string,
+	cause error,
+) error {
+	outcome := ProcessedDelivery
+	message := ""
+
+	if cause != nil {
+		outcome = FailedDelivery
+		message = cause.Error()
+	}
+
+	if _, err := db.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.delivery SET
+			outcome = $2,
+			error = $3,
+			attempts = attempts + 1,
+			processed_at = NOW()
+		WHERE id = $1`,
+		id,
+		outcome,
+		message,
+	); err != nil {
+		return fmt.Errorf("unable to complete delivery: %w", err)
+	}
+
+	return nil
+}
+
+// LoadDelivery loads the webhook delivery with the given ID.
+//
+// It returns false if there is no such delivery.
+func LoadDelivery(
+	ctx context.Context,
+	db *sql.DB,
+	id string,
+) (Delivery, bool, error) {
+	row := db.QueryRowContext(
+		ctx,
+		`SELECT
+			id,
+			event,
+			action,
+			COALESCE(repository_id, 0),
+			full_name,
+			payload
+		FROM dogmabrowser.delivery
+		WHERE id = $1`,
+		id,
+	)
+
+	var d Delivery
+	if err := row.Scan(
+		&d.ID,
+		&d.Event,
+		&d.Action,
+		&d.RepositoryID,
+		&d.FullName,
+		&d.Payload,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return Delivery{}, false, nil
+		}
+
+		return Delivery{}, false, fmt.Errorf("unable to load delivery: %w", err)
+	}
+
+	return d, true, nil
+}
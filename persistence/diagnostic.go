@@ -0,0 +1,86 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/go-github/v38/github"
+)
+
+// DiagnosticCategory is an enumeration of the categories of diagnostic
+// information produced when analyzing a repository.
+type DiagnosticCategory string
+
+const (
+	// SkippedDiagnostic indicates that some or all of the repository was not
+	// analyzed.
+	SkippedDiagnostic DiagnosticCategory = "skipped"
+
+	// LoadErrorDiagnostic indicates that a package could not be loaded, for
+	// example because it could not be parsed or its dependencies could not be
+	// resolved.
+	LoadErrorDiagnostic DiagnosticCategory = "load-error"
+
+	// TypeErrorDiagnostic indicates that a package failed to type-check.
+	TypeErrorDiagnostic DiagnosticCategory = "type-error"
+
+	// PanicDiagnostic indicates that static analysis of a package panicked.
+	PanicDiagnostic DiagnosticCategory = "panic"
+
+	// ConfigErrorDiagnostic indicates that the repository's configuration file
+	// is invalid.
+	ConfigErrorDiagnostic DiagnosticCategory = "config-error"
+)
+
+// Diagnostic is information about a problem that occurred when analyzing a
+// repository.
+type Diagnostic struct {
+	Category DiagnosticCategory
+	Package  string
+	Position string
+	Message  string
+}
+
+func syncDiagnostics(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+	commit string,
+	diags []Diagnostic,
+) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.diagnostic
+		WHERE repository_id = $1`,
+		r.GetID(),
+	); err != nil {
+		return fmt.Errorf("unable to remove diagnostics: %w", err)
+	}
+
+	for _, d := range diags {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO dogmabrowser.diagnostic (
+				repository_id,
+				commit_hash,
+				category,
+				package,
+				position,
+				message
+			) VALUES (
+				$1, $2, $3, $4, $5, $6
+			)`,
+			r.GetID(),
+			commit,
+			d.Category,
+			d.Package,
+			d.Position,
+			d.Message,
+		); err != nil {
+			return fmt.Errorf("unable to sync diagnostic: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/dogmatiq/configkit/message"
+)
+
+// SnapshotDiff describes the differences between two snapshots of the same
+// repository.
+type SnapshotDiff struct {
+	// AddedHandlers and RemovedHandlers are the handlers that are present in
+	// only one of the snapshots.
+	AddedHandlers   []SnapshotHandler
+	RemovedHandlers []SnapshotHandler
+
+	// ChangedKinds is the set of messages that are used in both snapshots, but
+	// with a different kind.
+	ChangedKinds []KindChange
+
+	// NewMismatches is the set of mismatches with other repositories that are
+	// present in the newer snapshot, but not the older one.
+	NewMismatches []Mismatch
+}
+
+// IsEmpty returns true if there are no differences between the snapshots.
+func (d SnapshotDiff) IsEmpty() bool {
+	return len(d.AddedHandlers) == 0 &&
+		len(d.RemovedHandlers) == 0 &&
+		len(d.ChangedKinds) == 0 &&
+		len(d.NewMismatches) == 0
+}
+
+// SnapshotHandler is a handler within a snapshot.
+type SnapshotHandler struct {
+	ApplicationName string
+	Key             string
+	Name            string
+	HandlerType     string
+}
+
+// KindChange describes a message that is used with a different kind in two
+// snapshots.
+type KindChange struct {
+	TypePackage string
+	TypeName    string
+	Before      []message.Kind
+	After       []message.Kind
+}
+
+// Mismatch describes a message that is used within a snapshot in a way that is
+// inconsistent with its use by an application in another repository.
+type Mismatch struct {
+	TypePackage string
+	TypeName    string
+	IsPointer   bool
+	Kind        message.Kind
+
+	OtherRepository  string
+	OtherApplication string
+	OtherIsPointer   bool
+	OtherKind        message.Kind
+}
+
+// DiffSnapshots compares two snapshots of a repository.
+//
+// Both commits must already have been snapshotted.
+func DiffSnapshots(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	before, after string,
+) (SnapshotDiff, error) {
+	var (
+		d   SnapshotDiff
+		err error
+	)
+
+	d.AddedHandlers, err = handlersOnlyIn(ctx, db, repoID, after, before)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+
+	d.RemovedHandlers, err = handlersOnlyIn(ctx, db, repoID, before, after)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+
+	d.ChangedKinds, err = changedKinds(ctx, db, repoID, before, after)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+
+	existing, err := snapshotMismatches(ctx, db, repoID, before)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+
+	current, err := snapshotMismatches(ctx, db, repoID, after)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+
+	for _, m := range current {
+		if !slices.Contains(existing, m) {
+			d.NewMismatches = append(d.NewMismatches, m)
+		}
+	}
+
+	return d, nil
+}
+
+// handlersOnlyIn returns the handlers that are present in the snapshot of
+// commit, but not in the snapshot of other.
+func handlersOnlyIn(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	commit, other string,
+) ([]SnapshotHandler, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT
+			a.name,
+			h.key,
+			h.name,
+			h.handler_type
+		FROM dogmabrowser.snapshot_handler AS h
+		INNER JOIN dogmabrowser.snapshot_application AS a
+		ON a.repository_id = h.repository_id
+		AND a.commit_hash = h.commit_hash
+		AND a.key = h.application_key
+		WHERE h.repository_id = $1
+		AND h.commit_hash = $2
+		AND NOT EXISTS (
+			SELECT *
+			FROM dogmabrowser.snapshot_handler AS x
+			WHERE x.repository_id = h.repository_id
+			AND x.commit_hash = $3
+			AND x.key = h.key
+		)
+		ORDER BY a.name, h.name`,
+		repoID,
+		commit,
+		other,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query handlers: %w", err)
+	}
+	defer rows.Close()
+
+	var handlers []SnapshotHandler
+
+	for rows.Next() {
+		var h SnapshotHandler
+
+		if err := rows.Scan(
+			&h.ApplicationName,
+			&h.Key,
+			&h.Name,
+			&h.HandlerType,
+		); err != nil {
+			return nil, fmt.Errorf("unable to scan handler: %w", err)
+		}
+
+		handlers = append(handlers, h)
+	}
+
+	return handlers, rows.Err()
+}
+
+// changedKinds returns the messages that are used in the snapshots of both
+// commits, but with a different kind.
+func changedKinds(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	before, after string,
+) ([]KindChange, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT DISTINCT
+			m.type_package,
+			m.type_name,
+			m.kind,
+			m.commit_hash = $3
+		FROM dogmabrowser.snapshot_handler_message AS m
+		WHERE m.repository_id = $1
+		AND m.commit_hash IN ($2, $3)
+		ORDER BY m.type_name, m.type_package, m.kind`,
+		repoID,
+		before,
+		after,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		changes []KindChange
+		index   = map[[2]string]int{}
+	)
+
+	for rows.Next() {
+		var (
+			pkg, name, kindText string
+			isAfter             bool
+		)
+
+		if err := rows.Scan(&pkg, &name, &kindText, &isAfter); err != nil {
+			return nil, fmt.Errorf("unable to scan message: %w", err)
+		}
+
+		kind, err := ParseKind(kindText)
+		if err != nil {
+			return nil, err
+		}
+
+		k := [2]string{pkg, name}
+		i, ok := index[k]
+		if !ok {
+			i = len(changes)
+			index[k] = i
+			changes = append(changes, KindChange{
+				TypePackage: pkg,
+				TypeName:    name,
+			})
+		}
+
+		if isAfter {
+			changes[i].After = append(changes[i].After, kind)
+		} else {
+			changes[i].Before = append(changes[i].Before, kind)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Only retain messages that are used in both snapshots; messages that are
+	// added or removed are reflected by the handler changes.
+	return slices.DeleteFunc(changes, func(c KindChange) bool {
+		return len(c.Before) == 0 ||
+			len(c.After) == 0 ||
+			slices.Equal(c.Before, c.After)
+	}), nil
+}
+
+// snapshotMismatches returns the mismatches between the messages used within
+// the snapshot of the given commit and the messages used by applications in
+// other repositories.
+func snapshotMismatches(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	commit string,
+) ([]Mismatch, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT DISTINCT
+			m.type_package,
+			m.type_name,
+			m.is_pointer,
+			m.kind,
+			r.full_name,
+			xa.name,
+			xm.is_pointer,
+			xm.kind
+		FROM dogmabrowser.snapshot_handler_message AS m
+		INNER JOIN dogmabrowser.type AS t
+		ON t.package = m.type_package
+		AND t.name = m.type_name
+		INNER JOIN dogmabrowser.handler_message AS xm
+		ON xm.type_id = t.id
+		INNER JOIN dogmabrowser.handler AS xh
+		ON xh.key = xm.handler_key
+		INNER JOIN dogmabrowser.application AS xa
+		ON xa.key = xh.application_key
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = xa.repository_id
+		WHERE m.repository_id = $1
+		AND m.commit_hash = $2
+		AND xa.repository_id != $1
+		AND (m.is_pointer != xm.is_pointer OR m.kind != xm.kind)
+		ORDER BY m.type_name, m.type_package, r.full_name, xa.name`,
+		repoID,
+		commit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query mismatches: %w", err)
+	}
+	defer rows.Close()
+
+	var mismatches []Mismatch
+
+	for rows.Next() {
+		var (
+			m               Mismatch
+			kind, otherKind string
+		)
+
+		if err := rows.Scan(
+			&m.TypePackage,
+			&m.TypeName,
+			&m.IsPointer,
+			&kind,
+			&m.OtherRepository,
+			&m.OtherApplication,
+			&m.OtherIsPointer,
+			&otherKind,
+		); err != nil {
+			return nil, fmt.Errorf("unable to scan mismatch: %w", err)
+		}
+
+		if m.Kind, err = ParseKind(kind); err != nil {
+			return nil, err
+		}
+
+		if m.OtherKind, err = ParseKind(otherKind); err != nil {
+			return nil, err
+		}
+
+		mismatches = append(mismatches, m)
+	}
+
+	return mismatches, rows.Err()
+}
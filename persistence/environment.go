@@ -0,0 +1,127 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v38/github"
+)
+
+// Environment is a named deployment environment of a repository, such as
+// "staging" or "production".
+type Environment struct {
+	// Name is the name of the environment.
+	Name string
+
+	// Pattern is the ref pattern that selects the ref deployed to the
+	// environment, such as "refs/heads/main" or "refs/tags/v*".
+	Pattern string
+
+	// Ref is the ref that matched Pattern, or an empty string if no ref
+	// matched.
+	Ref string
+
+	// CommitHash is the hash of the commit that Ref refers to. The commit must
+	// already have been snapshotted.
+	CommitHash string
+}
+
+// SyncEnvironments replaces the deployment environments of a repository.
+func SyncEnvironments(
+	ctx context.Context,
+	db *sql.DB,
+	r *github.Repository,
+	envs []Environment,
+) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.environment
+		WHERE repository_id = $1`,
+		r.GetID(),
+	); err != nil {
+		return fmt.Errorf("unable to remove environments: %w", err)
+	}
+
+	for i, e := range envs {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO dogmabrowser.environment (
+				repository_id,
+				name,
+				position,
+				pattern,
+				ref,
+				commit_hash
+			) VALUES (
+				$1, $2, $3, $4, $5, NULLIF($6, '')
+			)`,
+			r.GetID(),
+			e.Name,
+			i,
+			e.Pattern,
+			e.Ref,
+			e.CommitHash,
+		); err != nil {
+			return fmt.Errorf("unable to sync environment: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// IsEnvironmentRef returns true if the given ref matches the ref pattern of
+// any of the repository's deployment environments.
+func IsEnvironmentRef(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	ref string,
+) (bool, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT pattern
+		FROM dogmabrowser.environment
+		WHERE repository_id = $1`,
+		repoID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("unable to query environments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pattern string
+		if err := rows.Scan(&pattern); err != nil {
+			return false, fmt.Errorf("unable to scan environment: %w", err)
+		}
+
+		if MatchRef(pattern, ref) {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
+// MatchRef returns true if the fully-qualified ref matches the given pattern.
+//
+// The pattern is matched using path.Match(), such that "*" does not match the
+// "/" separator. A pattern that does not begin with "refs/" is treated as a
+// branch name.
+func MatchRef(pattern, ref string) bool {
+	if !strings.HasPrefix(pattern, "refs/") {
+		pattern = "refs/heads/" + pattern
+	}
+
+	ok, _ := path.Match(pattern, ref)
+	return ok
+}
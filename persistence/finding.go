@@ -0,0 +1,269 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dogmatiq/configkit/message"
+	"github.com/google/go-github/v38/github"
+)
+
+// FindingCategory is an enumeration of the kinds of breaking changes that are
+// detected when a repository is analyzed.
+type FindingCategory string
+
+const (
+	// RemovedProducerFinding is a finding that is recorded when a repository
+	// stops producing a message that is consumed by another repository.
+	RemovedProducerFinding FindingCategory = "removed-producer"
+
+	// RemovedConsumerFinding is a finding that is recorded when a repository
+	// stops handling a command that is executed by another repository.
+	RemovedConsumerFinding FindingCategory = "removed-consumer"
+
+	// KindChangedFinding is a finding that is recorded when a repository
+	// changes the kind of a message that is also used by another repository.
+	KindChangedFinding FindingCategory = "kind-changed"
+
+	// PointerChangedFinding is a finding that is recorded when a repository
+	// switches between pointer and non-pointer use of a message that another
+	// repository uses in the original form.
+	PointerChangedFinding FindingCategory = "pointer-changed"
+
+	// ApplicationKeyCollisionFinding is a finding that is recorded when a
+	// repository declares an application with the same identity key as an
+	// application in another repository, or another application in the same
+	// repository.
+	ApplicationKeyCollisionFinding FindingCategory = "application-key-collision"
+
+	// HandlerKeyCollisionFinding is a finding that is recorded when a
+	// repository declares a handler with the same identity key as a handler
+	// in a different application.
+	HandlerKeyCollisionFinding FindingCategory = "handler-key-collision"
+)
+
+// capturePreviousContracts records the messages used by the repository's
+// applications before they are replaced, so that detectFindings() can compare
+// them to the new messages.
+func capturePreviousContracts(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`CREATE TEMPORARY TABLE previous_contract ON COMMIT DROP AS
+		SELECT DISTINCT
+			m.type_id,
+			t.package AS type_package,
+			t.name AS type_name,
+			m.is_pointer,
+			m.kind,
+			m.is_produced,
+			m.is_consumed
+		FROM dogmabrowser.handler_message AS m
+		INNER JOIN dogmabrowser.type AS t
+		ON t.id = m.type_id
+		INNER JOIN dogmabrowser.handler AS h
+		ON h.key = m.handler_key
+		INNER JOIN dogmabrowser.application AS a
+		ON a.key = h.application_key
+		WHERE a.repository_id = $1`,
+		r.GetID(),
+	); err != nil {
+		return fmt.Errorf("unable to capture previous message contracts: %w", err)
+	}
+
+	return nil
+}
+
+// detectFindings compares the messages used by the repository's applications
+// to those captured by capturePreviousContracts() and records a finding for
+// each change that affects an application in another repository.
+func detectFindings(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+	commit string,
+) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`CREATE TEMPORARY TABLE current_contract ON COMMIT DROP AS
+		SELECT DISTINCT
+			m.type_id,
+			m.is_pointer,
+			m.kind,
+			m.is_produced,
+			m.is_consumed
+		FROM dogmabrowser.handler_message AS m
+		INNER JOIN dogmabrowser.handler AS h
+		ON h.key = m.handler_key
+		INNER JOIN dogmabrowser.application AS a
+		ON a.key = h.application_key
+		WHERE a.repository_id = $1`,
+		r.GetID(),
+	); err != nil {
+		return fmt.Errorf("unable to capture current message contracts: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.finding (
+			repository_id,
+			commit_hash,
+			affected_repository_id,
+			affected_application,
+			category,
+			type_package,
+			type_name,
+			previous_kind,
+			previous_is_pointer
+		)
+		SELECT DISTINCT
+			$1,
+			$2,
+			x.repository_id,
+			x.application_name,
+			CASE WHEN x.is_removed_producer THEN $3 ELSE $4 END,
+			x.type_package,
+			x.type_name,
+			x.kind,
+			x.is_pointer
+		FROM (
+			SELECT
+				xa.repository_id,
+				xa.name AS application_name,
+				p.type_package,
+				p.type_name,
+				p.kind,
+				p.is_pointer,
+				p.is_produced AND xm.is_consumed AND NOT EXISTS (
+					SELECT *
+					FROM current_contract AS c
+					WHERE c.type_id = p.type_id
+					AND c.is_produced
+				) AS is_removed_producer,
+				p.is_consumed AND xm.is_produced AND xm.kind = $5 AND NOT EXISTS (
+					SELECT *
+					FROM current_contract AS c
+					WHERE c.type_id = p.type_id
+					AND c.is_consumed
+				) AS is_removed_consumer
+			FROM previous_contract AS p
+			INNER JOIN dogmabrowser.handler_message AS xm
+			ON xm.type_id = p.type_id
+			INNER JOIN dogmabrowser.handler AS xh
+			ON xh.key = xm.handler_key
+			INNER JOIN dogmabrowser.application AS xa
+			ON xa.key = xh.application_key
+			WHERE xa.repository_id != $1
+		) AS x
+		WHERE x.is_removed_producer
+		OR x.is_removed_consumer`,
+		r.GetID(),
+		commit,
+		RemovedProducerFinding,
+		RemovedConsumerFinding,
+		message.CommandKind,
+	); err != nil {
+		return fmt.Errorf("unable to detect removed messages: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.finding (
+			repository_id,
+			commit_hash,
+			affected_repository_id,
+			affected_application,
+			category,
+			type_package,
+			type_name,
+			previous_kind,
+			current_kind
+		)
+		SELECT DISTINCT
+			$1,
+			$2,
+			xa.repository_id,
+			xa.name,
+			$3,
+			p.type_package,
+			p.type_name,
+			p.kind,
+			c.kind
+		FROM previous_contract AS p
+		INNER JOIN current_contract AS c
+		ON c.type_id = p.type_id
+		AND c.kind != p.kind
+		INNER JOIN dogmabrowser.handler_message AS xm
+		ON xm.type_id = p.type_id
+		INNER JOIN dogmabrowser.handler AS xh
+		ON xh.key = xm.handler_key
+		INNER JOIN dogmabrowser.application AS xa
+		ON xa.key = xh.application_key
+		WHERE xa.repository_id != $1
+		AND NOT EXISTS (
+			SELECT *
+			FROM current_contract AS x
+			WHERE x.type_id = p.type_id
+			AND x.kind = p.kind
+		)`,
+		r.GetID(),
+		commit,
+		KindChangedFinding,
+	); err != nil {
+		return fmt.Errorf("unable to detect message kind changes: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.finding (
+			repository_id,
+			commit_hash,
+			affected_repository_id,
+			affected_application,
+			category,
+			type_package,
+			type_name,
+			previous_is_pointer,
+			current_is_pointer
+		)
+		SELECT DISTINCT
+			$1,
+			$2,
+			xa.repository_id,
+			xa.name,
+			$3,
+			p.type_package,
+			p.type_name,
+			p.is_pointer,
+			c.is_pointer
+		FROM previous_contract AS p
+		INNER JOIN current_contract AS c
+		ON c.type_id = p.type_id
+		AND c.is_pointer != p.is_pointer
+		INNER JOIN dogmabrowser.handler_message AS xm
+		ON xm.type_id = p.type_id
+		AND xm.is_pointer = p.is_pointer
+		INNER JOIN dogmabrowser.handler AS xh
+		ON xh.key = xm.handler_key
+		INNER JOIN dogmabrowser.application AS xa
+		ON xa.key = xh.application_key
+		WHERE xa.repository_id != $1
+		AND NOT EXISTS (
+			SELECT *
+			FROM current_contract AS x
+			WHERE x.type_id = p.type_id
+			AND x.is_pointer = p.is_pointer
+		)`,
+		r.GetID(),
+		commit,
+		PointerChangedFinding,
+	); err != nil {
+		return fmt.Errorf("unable to detect pointer changes: %w", err)
+	}
+
+	return nil
+}
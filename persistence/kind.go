@@ -0,0 +1,25 @@
+package persistence
+
+import (
+	"fmt"
+
+	"github.com/dogmatiq/configkit/message"
+)
+
+// ParseKind parses a message kind as stored in the database.
+//
+// Kinds are stored as the text produced by message.Kind.String(), such as
+// "command" or "event", so they must be scanned into a string and parsed,
+// rather than being scanned into a message.Kind directly.
+func ParseKind(s string) (message.Kind, error) {
+	switch s {
+	case "command":
+		return message.CommandKind, nil
+	case "event":
+		return message.EventKind, nil
+	case "timeout":
+		return message.TimeoutKind, nil
+	default:
+		return 0, fmt.Errorf("unrecognized message kind: %q", s)
+	}
+}
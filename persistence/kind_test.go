@@ -0,0 +1,99 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/dogmatiq/configkit/message"
+)
+
+func TestParseKind(t *testing.T) {
+	db := sql.OpenDB(kindConnector{})
+	defer db.Close()
+
+	for _, want := range []message.Kind{
+		message.CommandKind,
+		message.EventKind,
+		message.TimeoutKind,
+	} {
+		t.Run(want.String(), func(t *testing.T) {
+			var s string
+			if err := db.QueryRowContext(
+				context.Background(),
+				"SELECT kind",
+				want,
+			).Scan(&s); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ParseKind(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != want {
+				t.Fatalf("unexpected kind: got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseKind_unrecognized(t *testing.T) {
+	for _, s := range []string{"", "1", "Event", "query"} {
+		if _, err := ParseKind(s); err == nil {
+			t.Fatalf("expected an error when parsing %q", s)
+		}
+	}
+}
+
+// kindConnector is a driver.Connector for a database that returns its single
+// argument as a single-row result, encoded as text in the same way as a
+// message.Kind that is written to a TEXT column.
+type kindConnector struct{}
+
+func (c kindConnector) Connect(context.Context) (driver.Conn, error) { return kindConn{}, nil }
+func (c kindConnector) Driver() driver.Driver                        { return nil }
+
+type kindConn struct{}
+
+func (kindConn) Prepare(string) (driver.Stmt, error) { return kindStmt{}, nil }
+func (kindConn) Close() error                        { return nil }
+func (kindConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+// CheckNamedValue accepts any argument, as is the case for the pgx driver.
+func (kindConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+type kindStmt struct{}
+
+func (kindStmt) Close() error  { return nil }
+func (kindStmt) NumInput() int { return 1 }
+
+func (kindStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+
+func (kindStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &kindRows{
+		value: args[0].(message.Kind).String(),
+	}, nil
+}
+
+type kindRows struct {
+	value string
+	done  bool
+}
+
+func (r *kindRows) Columns() []string { return []string{"kind"} }
+func (r *kindRows) Close() error      { return nil }
+
+func (r *kindRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+
+	r.done = true
+	dest[0] = r.value
+
+	return nil
+}
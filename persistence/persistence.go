@@ -4,8 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
-	"github.com/dogmatiq/configkit"
 	"github.com/google/go-github/v38/github"
 )
 
@@ -36,6 +36,42 @@ func RepositoryNeedsSync(
 	return ok, nil
 }
 
+// LoadAnalyzedCommits returns the hash of the most recently analyzed commit of
+// the default branch of every stored repository, keyed by repository ID.
+func LoadAnalyzedCommits(
+	ctx context.Context,
+	db *sql.DB,
+) (map[int64]string, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT
+			id,
+			commit_hash
+		FROM dogmabrowser.repository`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query repositories: %w", err)
+	}
+	defer rows.Close()
+
+	commits := map[int64]string{}
+
+	for rows.Next() {
+		var (
+			id     int64
+			commit string
+		)
+
+		if err := rows.Scan(&id, &commit); err != nil {
+			return nil, fmt.Errorf("unable to scan repository: %w", err)
+		}
+
+		commits[id] = commit
+	}
+
+	return commits, rows.Err()
+}
+
 func RemoveRepository(
 	ctx context.Context,
 	tx *sql.Tx,
@@ -47,6 +83,7 @@ func RemoveRepository(
 		ctx,
 		`UPDATE dogmabrowser.type SET
 			repository_id = NULL,
+			module_path = '',
 			url = NULL
 		WHERE repository_id = $1`,
 		repoID,
@@ -54,6 +91,17 @@ func RemoveRepository(
 		return fmt.Errorf("unable to decouple types from repository: %w", err)
 	}
 
+	// Pull requests may be recorded before the repository itself has been
+	// analyzed, so they are not removed by the foreign key cascade.
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.pull_request
+		WHERE repository_id = $1`,
+		repoID,
+	); err != nil {
+		return fmt.Errorf("unable to remove pull requests: %w", err)
+	}
+
 	if _, err := tx.ExecContext(
 		ctx,
 		`DELETE FROM dogmabrowser.repository
@@ -63,16 +111,224 @@ func RemoveRepository(
 		return fmt.Errorf("unable to remove repository: %w", err)
 	}
 
+	// Types that are also defined by other repositories are linked to one of
+	// those repositories instead.
+	if err := chooseCanonicalTypeSources(ctx, tx, repoID); err != nil {
+		return err
+	}
+
+	return markOrphanedDeclarationsStale(ctx, tx, repoID)
+}
+
+// RenameRepository updates the name and URL of a repository that has been
+// renamed or transferred to a new owner.
+//
+// The URLs of the types defined within the repository are rewritten to refer
+// to the new location. It does nothing if the repository has not been
+// analyzed.
+func RenameRepository(
+	ctx context.Context,
+	db *sql.DB,
+	r *github.Repository,
+) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	// The type URLs are rewritten first, as the previous URL of the repository
+	// is needed to identify the prefix that must be replaced.
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.type AS t SET
+			url = $2 || SUBSTRING(t.url FROM LENGTH(r.html_url) + 1)
+		FROM dogmabrowser.repository AS r
+		WHERE r.id = $1
+		AND t.repository_id = r.id
+		AND r.html_url != ''
+		AND STARTS_WITH(t.url, r.html_url || '/')`,
+		r.GetID(),
+		r.GetHTMLURL(),
+	); err != nil {
+		return fmt.Errorf("unable to rewrite type URLs: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.type_source AS s SET
+			url = $2 || SUBSTRING(s.url FROM LENGTH(r.html_url) + 1)
+		FROM dogmabrowser.repository AS r
+		WHERE r.id = $1
+		AND s.repository_id = r.id
+		AND r.html_url != ''
+		AND STARTS_WITH(s.url, r.html_url || '/')`,
+		r.GetID(),
+		r.GetHTMLURL(),
+	); err != nil {
+		return fmt.Errorf("unable to rewrite type source URLs: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.repository SET
+			full_name = $2,
+			html_url = $3
+		WHERE id = $1`,
+		r.GetID(),
+		r.GetFullName(),
+		r.GetHTMLURL(),
+	); err != nil {
+		return fmt.Errorf("unable to rename repository: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// MarkRepositoryStale marks a repository's analysis results as stale, such that
+// its default branch is analyzed again even if its commit has not changed.
+func MarkRepositoryStale(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.repository SET
+			is_stale = TRUE,
+			stale_since = NOW()
+		WHERE id = $1`,
+		repoID,
+	); err != nil {
+		return fmt.Errorf("unable to mark repository as stale: %w", err)
+	}
+
 	return nil
 }
 
+// MarkOutdatedRepositoriesStale marks the analysis results of each repository
+// that was analyzed by an analyzer with a fingerprint other than fingerprint as
+// stale. It returns the number of repositories that were marked.
+func MarkOutdatedRepositoriesStale(
+	ctx context.Context,
+	db *sql.DB,
+	fingerprint string,
+) (int64, error) {
+	res, err := db.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.repository SET
+			is_stale = TRUE,
+			stale_since = NOW()
+		WHERE analyzer_fingerprint != $1
+		AND is_stale = FALSE`,
+		fingerprint,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("unable to mark outdated repositories as stale: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// NextStaleRepository returns the ID of the stale repository that has gone the
+// longest without being analyzed.
+//
+// Repositories that are already queued, or that have been analyzed since they
+// were marked as stale without that analysis clearing the flag, such as when
+// they are no longer accessible, are excluded. It returns false if there are no
+// such repositories.
+func NextStaleRepository(
+	ctx context.Context,
+	db *sql.DB,
+) (int64, bool, error) {
+	row := db.QueryRowContext(
+		ctx,
+		`SELECT r.id
+		FROM dogmabrowser.repository AS r
+		WHERE r.is_stale = TRUE
+		AND NOT EXISTS (
+			SELECT *
+			FROM dogmabrowser.queue AS q
+			WHERE q.repository_id = r.id
+		)
+		AND NOT EXISTS (
+			SELECT *
+			FROM dogmabrowser.dead_letter AS d
+			WHERE d.repository_id = r.id
+		)
+		AND NOT EXISTS (
+			SELECT *
+			FROM dogmabrowser.analysis_run AS x
+			WHERE x.repository_id = r.id
+			AND x.started_at >= COALESCE(r.stale_since, '-infinity')
+		)
+		ORDER BY r.analyzed_at NULLS FIRST, r.id
+		LIMIT 1`,
+	)
+
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+
+		return 0, false, fmt.Errorf("unable to find stale repository: %w", err)
+	}
+
+	return id, true, nil
+}
+
+// SetRepositoryVisibility records whether a repository is private.
+func SetRepositoryVisibility(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	isPrivate bool,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.repository SET
+			is_private = $2
+		WHERE id = $1`,
+		repoID,
+		isPrivate,
+	); err != nil {
+		return fmt.Errorf("unable to set repository visibility: %w", err)
+	}
+
+	return nil
+}
+
+// AnalyzerInfo identifies the analyzer that produced a repository's analysis
+// results.
+type AnalyzerInfo struct {
+	// Version is the version of the browser.
+	Version string
+
+	// Fingerprint identifies the behavior of the analyzer. Results produced by
+	// an analyzer with a different fingerprint are considered stale.
+	Fingerprint string
+}
+
+// RepositoryMetadata is descriptive information about a repository that is
+// defined by the repository's configuration file.
+type RepositoryMetadata struct {
+	Team        string
+	Description string
+	Tags        []string
+}
+
 func SyncRepository(
 	ctx context.Context,
 	db *sql.DB,
 	r *github.Repository,
 	commit string,
-	apps []configkit.Application,
+	meta RepositoryMetadata,
+	an AnalyzerInfo,
+	apps []Application,
+	decls []Declaration,
 	defs []TypeDef,
+	diags []Diagnostic,
 ) error {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
@@ -85,27 +341,114 @@ func SyncRepository(
 		`INSERT INTO dogmabrowser.repository AS r (
 			id,
 			full_name,
-			commit_hash
+			html_url,
+			commit_hash,
+			analyzed_at,
+			team,
+			description,
+			is_private,
+			analyzer_version,
+			analyzer_fingerprint
 		) VALUES (
-			$1, $2, $3
+			$1, $2, $3, $4, NOW(), $5, $6, $7, $8, $9
 		) ON CONFLICT (id) DO UPDATE SET
 			full_name = excluded.full_name,
+			html_url = excluded.html_url,
 			commit_hash = excluded.commit_hash,
+			analyzed_at = excluded.analyzed_at,
+			team = excluded.team,
+			description = excluded.description,
+			is_private = excluded.is_private,
+			analyzer_version = excluded.analyzer_version,
+			analyzer_fingerprint = excluded.analyzer_fingerprint,
 			is_stale = FALSE`,
 		r.GetID(),
 		r.GetFullName(),
+		r.GetHTMLURL(),
 		commit,
+		meta.Team,
+		meta.Description,
+		r.GetPrivate(),
+		an.Version,
+		an.Fingerprint,
 	); err != nil {
 		return fmt.Errorf("unable to sync repository: %w", err)
 	}
 
+	if err := syncTags(ctx, tx, r, meta.Tags); err != nil {
+		return err
+	}
+
+	if err := capturePreviousContracts(ctx, tx, r); err != nil {
+		return err
+	}
+
+	if err := syncDeclarations(ctx, tx, r, decls); err != nil {
+		return err
+	}
+
 	if err := syncApplications(ctx, tx, r, apps); err != nil {
 		return err
 	}
 
+	if err := markOrphanedDeclarationsStale(ctx, tx, r.GetID()); err != nil {
+		return err
+	}
+
+	if err := detectFindings(ctx, tx, r, commit); err != nil {
+		return err
+	}
+
+	if err := detectCollisions(ctx, tx, r, commit); err != nil {
+		return err
+	}
+
 	if err := syncTypeDefs(ctx, tx, r, defs, commit); err != nil {
 		return err
 	}
 
+	if err := syncDiagnostics(ctx, tx, r, commit, diags); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
+
+func syncTags(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+	tags []string,
+) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.repository_tag
+		WHERE repository_id = $1`,
+		r.GetID(),
+	); err != nil {
+		return fmt.Errorf("unable to remove repository tags: %w", err)
+	}
+
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO dogmabrowser.repository_tag (
+				repository_id,
+				tag
+			) VALUES (
+				$1, $2
+			) ON CONFLICT DO NOTHING`,
+			r.GetID(),
+			t,
+		); err != nil {
+			return fmt.Errorf("unable to sync repository tag: %w", err)
+		}
+	}
+
+	return nil
+}
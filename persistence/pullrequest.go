@@ -0,0 +1,235 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// PullRequest is an open pull request of a repository.
+type PullRequest struct {
+	// Number is the pull request number, which is unique within the
+	// repository.
+	Number int
+
+	// HeadCommitHash is the hash of the commit at the head of the pull
+	// request.
+	HeadCommitHash string
+
+	// BaseRef is the name of the branch that the pull request is to be merged
+	// into.
+	BaseRef string
+
+	// BaseCommitHash is the hash of the commit at the head of the base branch.
+	BaseCommitHash string
+
+	// CheckRunID is the ID of the GitHub check run that reports the results of
+	// the analysis of the head commit, or zero if no check run has been
+	// created.
+	CheckRunID int64
+}
+
+// SavePullRequest records the current state of an open pull request.
+//
+// If the head or base of the pull request has changed since it was last
+// checked, it is returned by PendingPullRequests() until it is checked again.
+func SavePullRequest(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	pr PullRequest,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.pull_request (
+			repository_id,
+			number,
+			head_commit_hash,
+			base_ref,
+			base_commit_hash
+		) VALUES (
+			$1, $2, $3, $4, $5
+		) ON CONFLICT (repository_id, number) DO UPDATE SET
+			check_run_id = CASE
+				WHEN pull_request.head_commit_hash = excluded.head_commit_hash
+				THEN pull_request.check_run_id
+			END,
+			checked_commit_hash = CASE
+				WHEN pull_request.head_commit_hash = excluded.head_commit_hash
+				AND pull_request.base_commit_hash = excluded.base_commit_hash
+				THEN pull_request.checked_commit_hash
+			END,
+			last_error = CASE
+				WHEN pull_request.head_commit_hash = excluded.head_commit_hash
+				AND pull_request.base_commit_hash = excluded.base_commit_hash
+				THEN pull_request.last_error
+			END,
+			head_commit_hash = excluded.head_commit_hash,
+			base_ref = excluded.base_ref,
+			base_commit_hash = excluded.base_commit_hash,
+			updated_at = NOW()`,
+		repoID,
+		pr.Number,
+		pr.HeadCommitHash,
+		pr.BaseRef,
+		pr.BaseCommitHash,
+	); err != nil {
+		return fmt.Errorf("unable to save pull request: %w", err)
+	}
+
+	return nil
+}
+
+// RemovePullRequest removes a pull request that has been closed.
+func RemovePullRequest(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	number int,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.pull_request
+		WHERE repository_id = $1
+		AND number = $2`,
+		repoID,
+		number,
+	); err != nil {
+		return fmt.Errorf("unable to remove pull request: %w", err)
+	}
+
+	return nil
+}
+
+// PendingPullRequests returns the open pull requests of a repository that have
+// not been checked since their head or base last changed.
+func PendingPullRequests(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+) ([]PullRequest, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT
+			number,
+			head_commit_hash,
+			base_ref,
+			base_commit_hash,
+			COALESCE(check_run_id, 0)
+		FROM dogmabrowser.pull_request
+		WHERE repository_id = $1
+		AND checked_commit_hash IS NULL
+		ORDER BY number`,
+		repoID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query pull requests: %w", err)
+	}
+	defer rows.Close()
+
+	var prs []PullRequest
+
+	for rows.Next() {
+		var pr PullRequest
+
+		if err := rows.Scan(
+			&pr.Number,
+			&pr.HeadCommitHash,
+			&pr.BaseRef,
+			&pr.BaseCommitHash,
+			&pr.CheckRunID,
+		); err != nil {
+			return nil, fmt.Errorf("unable to scan pull request: %w", err)
+		}
+
+		prs = append(prs, pr)
+	}
+
+	return prs, rows.Err()
+}
+
+// SetPullRequestCheckRun records the ID of the check run that reports on the
+// given head commit of a pull request.
+func SetPullRequestCheckRun(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	pr PullRequest,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.pull_request SET
+			check_run_id = $4
+		WHERE repository_id = $1
+		AND number = $2
+		AND head_commit_hash = $3`,
+		repoID,
+		pr.Number,
+		pr.HeadCommitHash,
+		pr.CheckRunID,
+	); err != nil {
+		return fmt.Errorf("unable to record check run: %w", err)
+	}
+
+	return nil
+}
+
+// MarkPullRequestChecked marks a pull request as checked.
+//
+// It has no effect if the head or base of the pull request has changed since
+// pr was loaded, in which case the pull request remains pending.
+func MarkPullRequestChecked(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	pr PullRequest,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.pull_request SET
+			checked_commit_hash = head_commit_hash,
+			last_error = NULL
+		WHERE repository_id = $1
+		AND number = $2
+		AND head_commit_hash = $3
+		AND base_commit_hash = $4`,
+		repoID,
+		pr.Number,
+		pr.HeadCommitHash,
+		pr.BaseCommitHash,
+	); err != nil {
+		return fmt.Errorf("unable to mark pull request as checked: %w", err)
+	}
+
+	return nil
+}
+
+// RecordPullRequestError records the error that occurred when checking a pull
+// request.
+//
+// The pull request remains pending, such that it is checked again the next
+// time the repository is analyzed.
+func RecordPullRequestError(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	pr PullRequest,
+	cause error,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.pull_request SET
+			last_error = $4
+		WHERE repository_id = $1
+		AND number = $2
+		AND head_commit_hash = $3`,
+		repoID,
+		pr.Number,
+		pr.HeadCommitHash,
+		cause.Error(),
+	); err != nil {
+		return fmt.Errorf("unable to record pull request error: %w", err)
+	}
+
+	return nil
+}
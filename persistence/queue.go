@@ -0,0 +1,462 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Operation is an enumeration of the operations that can be performed on a
+// repository by a queued job.
+type Operation string
+
+const (
+	// AnalyzeOperation is an operation that analyzes a repository.
+	AnalyzeOperation Operation = "analyze"
+
+	// RemoveOperation is an operation that removes a repository's analysis
+	// results.
+	RemoveOperation Operation = "remove"
+)
+
+// Priority is the priority of a job. Jobs with a higher priority are claimed
+// before jobs with a lower priority.
+type Priority int
+
+const (
+	// BackgroundPriority is the priority used for bulk work, such as scanning
+	// all of the repositories that the GitHub application can access.
+	BackgroundPriority Priority = 0
+
+	// InteractivePriority is the priority used for work that is triggered by a
+	// change that a user expects to see reflected in the UI promptly, such as a
+	// push to a repository.
+	InteractivePriority Priority = 100
+)
+
+// Trigger is an enumeration of the reasons that a job is enqueued.
+type Trigger string
+
+const (
+	// StartupTrigger is the trigger used for jobs enqueued by the comparison of
+	// the repositories that the GitHub application can access with those stored
+	// in the database that is performed when the browser starts.
+	StartupTrigger Trigger = "startup"
+
+	// InstallationTrigger is the trigger used for jobs enqueued in response to
+	// changes to a GitHub application installation.
+	InstallationTrigger Trigger = "installation"
+
+	// PushTrigger is the trigger used for jobs enqueued in response to a push
+	// to a repository's default branch, a push of a new tag, or a push to a ref
+	// that is deployed to one of the repository's environments.
+	PushTrigger Trigger = "push"
+
+	// ReleaseTrigger is the trigger used for jobs enqueued in response to the
+	// publication of a GitHub release.
+	ReleaseTrigger Trigger = "release"
+
+	// PullRequestTrigger is the trigger used for jobs enqueued in response to
+	// a pull request being opened or updated.
+	PullRequestTrigger Trigger = "pull_request"
+
+	// RepositoryTrigger is the trigger used for jobs enqueued in response to a
+	// repository being transferred, archived, unarchived or deleted.
+	RepositoryTrigger Trigger = "repository"
+
+	// ReconcileTrigger is the trigger used for jobs enqueued by the periodic
+	// comparison of the repositories that the GitHub application can access
+	// with those stored in the database.
+	ReconcileTrigger Trigger = "reconcile"
+
+	// StaleTrigger is the trigger used for jobs enqueued to analyze a
+	// repository again because its analysis results are stale, such as when
+	// the browser has been upgraded.
+	StaleTrigger Trigger = "stale"
+
+	// BackfillTrigger is the trigger used for jobs enqueued to continue
+	// analyzing a repository's versions when there were too many to analyze
+	// within a single job.
+	BackfillTrigger Trigger = "backfill"
+
+	// ManualTrigger is the trigger used for jobs enqueued explicitly by a user
+	// of the browser.
+	ManualTrigger Trigger = "manual"
+)
+
+// Priority returns the priority of jobs enqueued by t.
+func (t Trigger) Priority() Priority {
+	switch t {
+	case PushTrigger, ReleaseTrigger, PullRequestTrigger, RepositoryTrigger, ManualTrigger:
+		return InteractivePriority
+	default:
+		return BackgroundPriority
+	}
+}
+
+// Job is a unit of work in the queue.
+type Job struct {
+	ID           int64
+	RepositoryID int64
+	Operation    Operation
+	Trigger      Trigger
+	Priority     Priority
+	Attempts     int
+
+	// ClaimToken identifies the claim under which the job was returned by
+	// ClaimJob(). It changes each time the job is claimed, such that a worker
+	// whose claim has expired can not modify a job that has since been
+	// claimed by another worker.
+	ClaimToken int64
+}
+
+// EnqueueJob adds a job to the queue.
+//
+// The job's priority is determined by its trigger, except for removals, which
+// always have interactive priority.
+//
+// If the most recently enqueued job for the same repository has the same
+// operation and has not yet been claimed, no new job is added. Instead, if the
+// existing job has a lower priority it adopts the priority and trigger of the
+// new job.
+// Any pending retry of the existing job is treated as a fresh attempt.
+func EnqueueJob(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	op Operation,
+	t Trigger,
+) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	if err := enqueueJob(ctx, tx, repoID, op, t); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func enqueueJob(
+	ctx context.Context,
+	tx *sql.Tx,
+	repoID int64,
+	op Operation,
+	t Trigger,
+) error {
+	p := t.Priority()
+	if op == RemoveOperation {
+		// Removals are cheap, and leaving stale results visible in the UI is
+		// worse than delaying an analysis, so they are always prioritized.
+		p = InteractivePriority
+	}
+
+	// Serialize enqueues for the same repository so that concurrent duplicate
+	// jobs are coalesced correctly.
+	if _, err := tx.ExecContext(
+		ctx,
+		`SELECT pg_advisory_xact_lock($1)`,
+		repoID,
+	); err != nil {
+		return fmt.Errorf("unable to lock queue: %w", err)
+	}
+
+	// A job that is waiting to be retried after a failure is made available
+	// immediately, and its attempts are reset, otherwise the new request would
+	// inherit its backoff delay and be dead-lettered after fewer attempts.
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.queue SET
+			priority = GREATEST(priority, $3),
+			trigger = CASE WHEN priority < $3 THEN $4 ELSE trigger END,
+			not_before = LEAST(not_before, NOW()),
+			attempts = 0
+		WHERE id = (
+			SELECT MAX(id)
+			FROM dogmabrowser.queue
+			WHERE repository_id = $1
+		)
+		AND operation = $2
+		AND claimed_until IS NULL`,
+		repoID,
+		op,
+		p,
+		t,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to coalesce job: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n != 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.queue (
+			repository_id,
+			operation,
+			trigger,
+			priority
+		) VALUES (
+			$1, $2, $3, $4
+		)`,
+		repoID,
+		op,
+		t,
+		p,
+	); err != nil {
+		return fmt.Errorf("unable to enqueue job: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimJob claims the highest priority job in the queue that is not already
+// claimed. Jobs with the same priority are claimed in the order they were
+// enqueued.
+//
+// Only the oldest job for each repository may be claimed, such that jobs for
+// the same repository are never processed concurrently, and are always
+// processed in the order they were enqueued.
+//
+// Jobs that have been scheduled for a retry are not claimed until their retry
+// time has been reached.
+//
+// The claim is held until the job is completed, or until the claim timeout
+// elapses, at which point the job is made available to be claimed again. The
+// claim may be extended using ExtendClaim().
+func ClaimJob(
+	ctx context.Context,
+	db *sql.DB,
+	timeout time.Duration,
+) (Job, bool, error) {
+	row := db.QueryRowContext(
+		ctx,
+		`UPDATE dogmabrowser.queue SET
+			claimed_until = NOW() + $1 * INTERVAL '1 millisecond',
+			claim_token = claim_token + 1
+		WHERE id = (
+			SELECT q.id
+			FROM dogmabrowser.queue AS q
+			WHERE (
+				q.claimed_until IS NULL
+				OR q.claimed_until < NOW()
+			)
+			AND q.not_before <= NOW()
+			AND NOT EXISTS (
+				SELECT *
+				FROM dogmabrowser.queue AS x
+				WHERE x.repository_id = q.repository_id
+				AND x.id < q.id
+			)
+			ORDER BY (
+				-- A job inherits the priority of any later job for the same
+				-- repository, otherwise urgent work could be held up behind
+				-- the repository's existing background work.
+				SELECT MAX(x.priority)
+				FROM dogmabrowser.queue AS x
+				WHERE x.repository_id = q.repository_id
+			) DESC, q.id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, repository_id, operation, trigger, priority, attempts, claim_token`,
+		timeout.Milliseconds(),
+	)
+
+	var j Job
+	if err := row.Scan(
+		&j.ID,
+		&j.RepositoryID,
+		&j.Operation,
+		&j.Trigger,
+		&j.Priority,
+		&j.Attempts,
+		&j.ClaimToken,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return Job{}, false, nil
+		}
+
+		return Job{}, false, fmt.Errorf("unable to claim job: %w", err)
+	}
+
+	return j, true, nil
+}
+
+// ExtendClaim extends the claim on a job that was returned by ClaimJob(), such
+// that it expires after the given timeout.
+//
+// It returns false if the claim has already been lost, either because it
+// expired and the job was claimed again, or because the job is no longer in
+// the queue.
+func ExtendClaim(
+	ctx context.Context,
+	db *sql.DB,
+	job Job,
+	timeout time.Duration,
+) (bool, error) {
+	res, err := db.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.queue SET
+			claimed_until = NOW() + $3 * INTERVAL '1 millisecond'
+		WHERE id = $1
+		AND claim_token = $2`,
+		job.ID,
+		job.ClaimToken,
+		timeout.Milliseconds(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("unable to extend claim: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	return n != 0, err
+}
+
+// CompleteJob removes a job that was returned by ClaimJob() from the queue
+// once it has been processed successfully.
+//
+// It has no effect if the claim on the job has been lost.
+func CompleteJob(
+	ctx context.Context,
+	db *sql.DB,
+	job Job,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.queue
+		WHERE id = $1
+		AND claim_token = $2`,
+		job.ID,
+		job.ClaimToken,
+	); err != nil {
+		return fmt.Errorf("unable to complete job: %w", err)
+	}
+
+	return nil
+}
+
+// RetryJob releases the claim on a failed job and schedules it to be retried
+// after the given delay.
+//
+// It has no effect if the claim on the job has been lost.
+func RetryJob(
+	ctx context.Context,
+	db *sql.DB,
+	job Job,
+	delay time.Duration,
+	cause error,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.queue SET
+			attempts = attempts + 1,
+			not_before = NOW() + $3 * INTERVAL '1 millisecond',
+			last_error = $4,
+			claimed_until = NULL
+		WHERE id = $1
+		AND claim_token = $2`,
+		job.ID,
+		job.ClaimToken,
+		delay.Milliseconds(),
+		cause.Error(),
+	); err != nil {
+		return fmt.Errorf("unable to schedule job for retry: %w", err)
+	}
+
+	return nil
+}
+
+// DeadLetterJob removes a failed job from the queue and adds it to the
+// dead-letter list.
+//
+// It has no effect if the claim on the job has been lost.
+func DeadLetterJob(
+	ctx context.Context,
+	db *sql.DB,
+	job Job,
+	cause error,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`WITH j AS (
+			DELETE FROM dogmabrowser.queue
+			WHERE id = $1
+			AND claim_token = $2
+			RETURNING repository_id, operation, attempts, enqueued_at
+		)
+		INSERT INTO dogmabrowser.dead_letter (
+			repository_id,
+			operation,
+			attempts,
+			error,
+			enqueued_at
+		)
+		SELECT
+			repository_id,
+			operation,
+			attempts + 1,
+			$3,
+			enqueued_at
+		FROM j`,
+		job.ID,
+		job.ClaimToken,
+		cause.Error(),
+	); err != nil {
+		return fmt.Errorf("unable to move job to dead-letter list: %w", err)
+	}
+
+	return nil
+}
+
+// RetryDeadLetter removes a job from the dead-letter list and adds it back to
+// the queue with the given trigger.
+//
+// It returns false if there is no such job on the dead-letter list.
+func RetryDeadLetter(
+	ctx context.Context,
+	db *sql.DB,
+	id int64,
+	t Trigger,
+) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	row := tx.QueryRowContext(
+		ctx,
+		`DELETE FROM dogmabrowser.dead_letter
+		WHERE id = $1
+		RETURNING repository_id, operation`,
+		id,
+	)
+
+	var (
+		repoID int64
+		op     Operation
+	)
+
+	if err := row.Scan(&repoID, &op); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("unable to remove job from dead-letter list: %w", err)
+	}
+
+	if err := enqueueJob(ctx, tx, repoID, op, t); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
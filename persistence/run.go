@@ -0,0 +1,117 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RunOutcome is an enumeration of the possible outcomes of an analysis run.
+type RunOutcome string
+
+const (
+	// AnalyzedOutcome indicates that the repository was analyzed and the
+	// results were stored.
+	AnalyzedOutcome RunOutcome = "analyzed"
+
+	// SkippedOutcome indicates that the repository was not analyzed, for
+	// example because it is archived or does not contain a Go module.
+	SkippedOutcome RunOutcome = "skipped"
+
+	// UnchangedOutcome indicates that the repository was not analyzed because
+	// its current commit has already been analyzed.
+	UnchangedOutcome RunOutcome = "unchanged"
+
+	// UnavailableOutcome indicates that the repository was not analyzed
+	// because it does not exist or is not accessible.
+	UnavailableOutcome RunOutcome = "unavailable"
+
+	// FailedOutcome indicates that the analysis failed with an error.
+	FailedOutcome RunOutcome = "failed"
+)
+
+// maxRunsPerRepository is the number of analysis runs retained for each
+// repository. Older runs are deleted when a new run is recorded.
+const maxRunsPerRepository = 100
+
+// AnalysisRun is a record of a single attempt to analyze a repository.
+type AnalysisRun struct {
+	RepositoryID     int64
+	FullName         string
+	CommitHash       string
+	Trigger          Trigger
+	StartedAt        time.Time
+	EndedAt          time.Time
+	DownloadDuration time.Duration
+	LoadDuration     time.Duration
+	SyncDuration     time.Duration
+	Outcome          RunOutcome
+	Error            string
+}
+
+// RecordRun stores a record of an analysis run.
+func RecordRun(
+	ctx context.Context,
+	db *sql.DB,
+	r AnalysisRun,
+) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.analysis_run (
+			repository_id,
+			full_name,
+			commit_hash,
+			trigger,
+			started_at,
+			ended_at,
+			download_ms,
+			load_ms,
+			sync_ms,
+			outcome,
+			error
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		)`,
+		r.RepositoryID,
+		r.FullName,
+		r.CommitHash,
+		r.Trigger,
+		r.StartedAt,
+		r.EndedAt,
+		r.DownloadDuration.Milliseconds(),
+		r.LoadDuration.Milliseconds(),
+		r.SyncDuration.Milliseconds(),
+		r.Outcome,
+		r.Error,
+	); err != nil {
+		return fmt.Errorf("unable to record analysis run: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.analysis_run
+		WHERE repository_id = $1
+		AND id < (
+			SELECT MIN(id) FROM (
+				SELECT id
+				FROM dogmabrowser.analysis_run
+				WHERE repository_id = $1
+				ORDER BY id DESC
+				LIMIT $2
+			) AS x
+		)`,
+		r.RepositoryID,
+		maxRunsPerRepository,
+	); err != nil {
+		return fmt.Errorf("unable to prune analysis runs: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -8,8 +8,42 @@ CREATE TABLE
         is_stale BOOLEAN NOT NULL DEFAULT FALSE
     );
 
+ALTER TABLE dogmabrowser.repository
+ADD COLUMN IF NOT EXISTS html_url TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE dogmabrowser.repository
+ADD COLUMN IF NOT EXISTS analyzed_at TIMESTAMPTZ;
+
+ALTER TABLE dogmabrowser.repository
+ADD COLUMN IF NOT EXISTS team TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE dogmabrowser.repository
+ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE dogmabrowser.repository
+ADD COLUMN IF NOT EXISTS is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE dogmabrowser.repository
+ADD COLUMN IF NOT EXISTS stale_since TIMESTAMPTZ;
+
+ALTER TABLE dogmabrowser.repository
+ADD COLUMN IF NOT EXISTS analyzer_version TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE dogmabrowser.repository
+ADD COLUMN IF NOT EXISTS analyzer_fingerprint TEXT NOT NULL DEFAULT '';
+
 CREATE INDEX IF NOT EXISTS repository_stale_idx ON dogmabrowser.repository (is_stale);
 
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.repository_tag (
+        repository_id INT NOT NULL,
+        tag TEXT NOT NULL,
+        PRIMARY KEY (repository_id, tag),
+        CONSTRAINT repository_fkey FOREIGN KEY (repository_id) REFERENCES dogmabrowser.repository (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS repository_tag_tag_idx ON dogmabrowser.repository_tag (tag);
+
 CREATE TABLE
     IF NOT EXISTS dogmabrowser.type (
         id SERIAL PRIMARY KEY,
@@ -23,8 +57,95 @@ CREATE TABLE
         CONSTRAINT repository_fkey FOREIGN KEY (repository_id) REFERENCES dogmabrowser.repository (id) ON DELETE SET NULL
     );
 
+ALTER TABLE dogmabrowser.type
+ADD COLUMN IF NOT EXISTS module_path TEXT NOT NULL DEFAULT '';
+
 CREATE INDEX IF NOT EXISTS type_repository_idx ON dogmabrowser.type (repository_id);
 
+ALTER TABLE dogmabrowser.type
+ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'named',
+ADD COLUMN IF NOT EXISTS origin_id INT REFERENCES dogmabrowser.type (id) ON DELETE RESTRICT,
+ADD COLUMN IF NOT EXISTS elem_id INT REFERENCES dogmabrowser.type (id) ON DELETE RESTRICT,
+ADD COLUMN IF NOT EXISTS key_id INT REFERENCES dogmabrowser.type (id) ON DELETE RESTRICT,
+ADD COLUMN IF NOT EXISTS length TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS type_origin_idx ON dogmabrowser.type (origin_id);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.type_argument (
+        type_id INT NOT NULL,
+        position INT NOT NULL,
+        argument_id INT NOT NULL,
+        PRIMARY KEY (type_id, position),
+        CONSTRAINT type_fkey FOREIGN KEY (type_id) REFERENCES dogmabrowser.type (id) ON DELETE CASCADE,
+        CONSTRAINT argument_fkey FOREIGN KEY (argument_id) REFERENCES dogmabrowser.type (id) ON DELETE RESTRICT
+    );
+
+CREATE INDEX IF NOT EXISTS type_argument_argument_idx ON dogmabrowser.type_argument (argument_id);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.type_source (
+        type_id INT NOT NULL,
+        repository_id INT NOT NULL,
+        module_path TEXT NOT NULL,
+        url TEXT NOT NULL,
+        docs TEXT NOT NULL,
+        is_owner BOOLEAN NOT NULL,
+        PRIMARY KEY (type_id, repository_id),
+        CONSTRAINT type_fkey FOREIGN KEY (type_id) REFERENCES dogmabrowser.type (id) ON DELETE CASCADE,
+        CONSTRAINT repository_fkey FOREIGN KEY (repository_id) REFERENCES dogmabrowser.repository (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS type_source_repository_idx ON dogmabrowser.type_source (repository_id);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.type_field (
+        type_id INT NOT NULL,
+        repository_id INT NOT NULL,
+        position INT NOT NULL,
+        name TEXT NOT NULL,
+        type_package TEXT NOT NULL,
+        type_name TEXT NOT NULL,
+        is_pointer BOOLEAN NOT NULL,
+        tag TEXT NOT NULL,
+        docs TEXT NOT NULL,
+        is_embedded BOOLEAN NOT NULL,
+        PRIMARY KEY (type_id, repository_id, position),
+        CONSTRAINT type_source_fkey FOREIGN KEY (type_id, repository_id) REFERENCES dogmabrowser.type_source (type_id, repository_id) ON DELETE CASCADE
+    );
+
+INSERT INTO
+    dogmabrowser.type_source (
+        type_id,
+        repository_id,
+        module_path,
+        url,
+        docs,
+        is_owner
+    )
+SELECT
+    t.id,
+    t.repository_id,
+    t.module_path,
+    COALESCE(t.url, ''),
+    COALESCE(t.docs, ''),
+    t.module_path != ''
+    AND (
+        t.package = t.module_path
+        OR STARTS_WITH (t.package, t.module_path || '/')
+    )
+FROM
+    dogmabrowser.type AS t
+WHERE
+    t.repository_id IS NOT NULL
+    AND NOT EXISTS (
+        SELECT
+            *
+        FROM
+            dogmabrowser.type_source
+    )
+ON CONFLICT DO NOTHING;
+
 CREATE TABLE
     IF NOT EXISTS dogmabrowser.application (
         key TEXT PRIMARY KEY,
@@ -37,6 +158,9 @@ CREATE TABLE
         CONSTRAINT type_fkey FOREIGN KEY (type_id) REFERENCES dogmabrowser.type (id) ON DELETE RESTRICT
     );
 
+ALTER TABLE dogmabrowser.application
+ADD COLUMN IF NOT EXISTS module_path TEXT NOT NULL DEFAULT '';
+
 CREATE INDEX IF NOT EXISTS application_repository_idx ON dogmabrowser.application (repository_id);
 
 CREATE INDEX IF NOT EXISTS application_type_idx ON dogmabrowser.application (type_id);
@@ -58,6 +182,22 @@ CREATE INDEX IF NOT EXISTS handler_application_idx ON dogmabrowser.handler (appl
 
 CREATE INDEX IF NOT EXISTS handler_type_idx ON dogmabrowser.handler (type_id);
 
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.application_build (
+        application_key TEXT NOT NULL,
+        build_config TEXT NOT NULL,
+        PRIMARY KEY (application_key, build_config),
+        CONSTRAINT application_fkey FOREIGN KEY (application_key) REFERENCES dogmabrowser.application (key) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.handler_build (
+        handler_key TEXT NOT NULL,
+        build_config TEXT NOT NULL,
+        PRIMARY KEY (handler_key, build_config),
+        CONSTRAINT handler_fkey FOREIGN KEY (handler_key) REFERENCES dogmabrowser.handler (key) ON DELETE CASCADE
+    );
+
 CREATE TABLE
     IF NOT EXISTS dogmabrowser.handler_message (
         handler_key TEXT NOT NULL,
@@ -77,3 +217,287 @@ CREATE INDEX IF NOT EXISTS handler_message_type_idx ON dogmabrowser.handler_mess
 CREATE INDEX IF NOT EXISTS handler_message_produced_idx ON dogmabrowser.handler_message (is_produced, type_id);
 
 CREATE INDEX IF NOT EXISTS handler_message_consumed_idx ON dogmabrowser.handler_message (is_consumed, type_id);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.handler_message_build (
+        handler_key TEXT NOT NULL,
+        type_id INT NOT NULL,
+        is_pointer BOOLEAN NOT NULL,
+        build_config TEXT NOT NULL,
+        PRIMARY KEY (handler_key, type_id, is_pointer, build_config),
+        CONSTRAINT handler_message_fkey FOREIGN KEY (handler_key, type_id, is_pointer) REFERENCES dogmabrowser.handler_message (handler_key, type_id, is_pointer) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.diagnostic (
+        id SERIAL PRIMARY KEY,
+        repository_id INT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        category TEXT NOT NULL,
+        package TEXT NOT NULL,
+        position TEXT NOT NULL,
+        message TEXT NOT NULL,
+        CONSTRAINT repository_fkey FOREIGN KEY (repository_id) REFERENCES dogmabrowser.repository (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS diagnostic_repository_idx ON dogmabrowser.diagnostic (repository_id);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.finding (
+        id BIGSERIAL PRIMARY KEY,
+        repository_id INT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        affected_repository_id INT NOT NULL,
+        affected_application TEXT NOT NULL,
+        category TEXT NOT NULL,
+        type_package TEXT NOT NULL,
+        type_name TEXT NOT NULL,
+        previous_kind TEXT,
+        current_kind TEXT,
+        previous_is_pointer BOOLEAN,
+        current_is_pointer BOOLEAN,
+        detected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        CONSTRAINT repository_fkey FOREIGN KEY (repository_id) REFERENCES dogmabrowser.repository (id) ON DELETE CASCADE,
+        CONSTRAINT affected_repository_fkey FOREIGN KEY (affected_repository_id) REFERENCES dogmabrowser.repository (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS finding_repository_idx ON dogmabrowser.finding (repository_id, id);
+
+CREATE INDEX IF NOT EXISTS finding_affected_repository_idx ON dogmabrowser.finding (affected_repository_id, id);
+
+ALTER TABLE dogmabrowser.finding
+ADD COLUMN IF NOT EXISTS identity_key TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.identity_declaration (
+        repository_id INT NOT NULL,
+        entity TEXT NOT NULL,
+        key TEXT NOT NULL,
+        name TEXT NOT NULL,
+        application_key TEXT NOT NULL,
+        application_name TEXT NOT NULL,
+        type_package TEXT NOT NULL,
+        type_name TEXT NOT NULL,
+        module_path TEXT NOT NULL,
+        PRIMARY KEY (repository_id, entity, key, application_key, type_package, type_name),
+        CONSTRAINT repository_fkey FOREIGN KEY (repository_id) REFERENCES dogmabrowser.repository (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS identity_declaration_key_idx ON dogmabrowser.identity_declaration (entity, key);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.snapshot (
+        repository_id INT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        committed_at TIMESTAMPTZ NOT NULL,
+        skip_reason TEXT NOT NULL,
+        analyzed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        PRIMARY KEY (repository_id, commit_hash),
+        CONSTRAINT repository_fkey FOREIGN KEY (repository_id) REFERENCES dogmabrowser.repository (id) ON DELETE CASCADE
+    );
+
+ALTER TABLE dogmabrowser.snapshot
+ADD COLUMN IF NOT EXISTS has_message_shapes BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.snapshot_message_type (
+        repository_id INT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        type_package TEXT NOT NULL,
+        type_name TEXT NOT NULL,
+        PRIMARY KEY (repository_id, commit_hash, type_package, type_name),
+        CONSTRAINT snapshot_fkey FOREIGN KEY (repository_id, commit_hash) REFERENCES dogmabrowser.snapshot (repository_id, commit_hash) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.snapshot_message_field (
+        repository_id INT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        type_package TEXT NOT NULL,
+        type_name TEXT NOT NULL,
+        position INT NOT NULL,
+        name TEXT NOT NULL,
+        field_type TEXT NOT NULL,
+        tag TEXT NOT NULL,
+        PRIMARY KEY (repository_id, commit_hash, type_package, type_name, position),
+        CONSTRAINT snapshot_message_type_fkey FOREIGN KEY (repository_id, commit_hash, type_package, type_name) REFERENCES dogmabrowser.snapshot_message_type (repository_id, commit_hash, type_package, type_name) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.snapshot_application (
+        repository_id INT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        key TEXT NOT NULL,
+        name TEXT NOT NULL,
+        type_package TEXT NOT NULL,
+        type_name TEXT NOT NULL,
+        is_pointer BOOLEAN NOT NULL,
+        module_path TEXT NOT NULL,
+        PRIMARY KEY (repository_id, commit_hash, key),
+        CONSTRAINT snapshot_fkey FOREIGN KEY (repository_id, commit_hash) REFERENCES dogmabrowser.snapshot (repository_id, commit_hash) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS snapshot_application_key_idx ON dogmabrowser.snapshot_application (key);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.snapshot_handler (
+        repository_id INT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        key TEXT NOT NULL,
+        application_key TEXT NOT NULL,
+        name TEXT NOT NULL,
+        handler_type TEXT NOT NULL,
+        type_package TEXT NOT NULL,
+        type_name TEXT NOT NULL,
+        is_pointer BOOLEAN NOT NULL,
+        PRIMARY KEY (repository_id, commit_hash, key),
+        CONSTRAINT snapshot_fkey FOREIGN KEY (repository_id, commit_hash) REFERENCES dogmabrowser.snapshot (repository_id, commit_hash) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS snapshot_handler_key_idx ON dogmabrowser.snapshot_handler (key);
+
+CREATE INDEX IF NOT EXISTS snapshot_handler_application_idx ON dogmabrowser.snapshot_handler (application_key);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.snapshot_handler_message (
+        repository_id INT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        handler_key TEXT NOT NULL,
+        type_package TEXT NOT NULL,
+        type_name TEXT NOT NULL,
+        is_pointer BOOLEAN NOT NULL,
+        kind TEXT NOT NULL,
+        is_produced BOOLEAN NOT NULL,
+        is_consumed BOOLEAN NOT NULL,
+        PRIMARY KEY (repository_id, commit_hash, handler_key, type_package, type_name, is_pointer),
+        CONSTRAINT snapshot_fkey FOREIGN KEY (repository_id, commit_hash) REFERENCES dogmabrowser.snapshot (repository_id, commit_hash) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.change (
+        id BIGSERIAL PRIMARY KEY,
+        repository_id INT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        previous_commit_hash TEXT NOT NULL,
+        entity TEXT NOT NULL,
+        action TEXT NOT NULL,
+        key TEXT NOT NULL,
+        name TEXT NOT NULL,
+        message_type TEXT NOT NULL,
+        before_value TEXT NOT NULL,
+        after_value TEXT NOT NULL,
+        recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        CONSTRAINT snapshot_fkey FOREIGN KEY (repository_id, commit_hash) REFERENCES dogmabrowser.snapshot (repository_id, commit_hash) ON DELETE CASCADE,
+        CONSTRAINT previous_snapshot_fkey FOREIGN KEY (repository_id, previous_commit_hash) REFERENCES dogmabrowser.snapshot (repository_id, commit_hash) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS change_repository_idx ON dogmabrowser.change (repository_id, id);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.version (
+        repository_id INT NOT NULL,
+        tag TEXT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        is_release BOOLEAN NOT NULL,
+        release_name TEXT NOT NULL,
+        PRIMARY KEY (repository_id, tag),
+        CONSTRAINT snapshot_fkey FOREIGN KEY (repository_id, commit_hash) REFERENCES dogmabrowser.snapshot (repository_id, commit_hash) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.environment (
+        repository_id INT NOT NULL,
+        name TEXT NOT NULL,
+        position INT NOT NULL,
+        pattern TEXT NOT NULL,
+        ref TEXT NOT NULL,
+        commit_hash TEXT,
+        resolved_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        PRIMARY KEY (repository_id, name),
+        CONSTRAINT repository_fkey FOREIGN KEY (repository_id) REFERENCES dogmabrowser.repository (id) ON DELETE CASCADE,
+        CONSTRAINT snapshot_fkey FOREIGN KEY (repository_id, commit_hash) REFERENCES dogmabrowser.snapshot (repository_id, commit_hash)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.pull_request (
+        repository_id INT NOT NULL,
+        number INT NOT NULL,
+        head_commit_hash TEXT NOT NULL,
+        base_ref TEXT NOT NULL,
+        base_commit_hash TEXT NOT NULL,
+        checked_commit_hash TEXT,
+        check_run_id BIGINT,
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        PRIMARY KEY (repository_id, number)
+    );
+
+ALTER TABLE dogmabrowser.pull_request
+ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.queue (
+        id BIGSERIAL PRIMARY KEY,
+        repository_id BIGINT NOT NULL,
+        operation TEXT NOT NULL,
+        trigger TEXT NOT NULL,
+        priority INT NOT NULL DEFAULT 0,
+        enqueued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        claimed_until TIMESTAMPTZ,
+        attempts INT NOT NULL DEFAULT 0,
+        not_before TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        last_error TEXT
+    );
+
+ALTER TABLE dogmabrowser.queue
+ADD COLUMN IF NOT EXISTS claim_token BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS queue_repository_idx ON dogmabrowser.queue (repository_id, id);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.dead_letter (
+        id BIGSERIAL PRIMARY KEY,
+        repository_id BIGINT NOT NULL,
+        operation TEXT NOT NULL,
+        attempts INT NOT NULL,
+        error TEXT NOT NULL,
+        enqueued_at TIMESTAMPTZ NOT NULL,
+        failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.analysis_run (
+        id BIGSERIAL PRIMARY KEY,
+        repository_id BIGINT NOT NULL,
+        full_name TEXT NOT NULL,
+        commit_hash TEXT NOT NULL,
+        trigger TEXT NOT NULL,
+        started_at TIMESTAMPTZ NOT NULL,
+        ended_at TIMESTAMPTZ NOT NULL,
+        download_ms BIGINT NOT NULL,
+        load_ms BIGINT NOT NULL,
+        sync_ms BIGINT NOT NULL,
+        outcome TEXT NOT NULL,
+        error TEXT NOT NULL
+    );
+
+CREATE INDEX IF NOT EXISTS analysis_run_repository_idx ON dogmabrowser.analysis_run (repository_id, id);
+
+CREATE INDEX IF NOT EXISTS analysis_run_started_idx ON dogmabrowser.analysis_run (started_at);
+
+CREATE TABLE
+    IF NOT EXISTS dogmabrowser.delivery (
+        id TEXT PRIMARY KEY,
+        event TEXT NOT NULL,
+        action TEXT NOT NULL,
+        repository_id BIGINT,
+        full_name TEXT NOT NULL,
+        payload BYTEA NOT NULL,
+        outcome TEXT NOT NULL,
+        error TEXT NOT NULL DEFAULT '',
+        receipts INT NOT NULL DEFAULT 1,
+        attempts INT NOT NULL DEFAULT 0,
+        received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        processed_at TIMESTAMPTZ
+    );
+
+CREATE INDEX IF NOT EXISTS delivery_received_idx ON dogmabrowser.delivery (received_at);
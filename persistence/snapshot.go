@@ -0,0 +1,346 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v38/github"
+)
+
+// Snapshot is an immutable record of the applications within a specific commit
+// of a repository.
+type Snapshot struct {
+	// CommitHash is the hash of the commit that was analyzed.
+	CommitHash string
+
+	// CommittedAt is the time of the commit. Snapshots of the same repository
+	// are ordered by this time.
+	CommittedAt time.Time
+
+	// SkipReason is a human-readable explanation of why the commit could not
+	// be analyzed, or an empty string if it was analyzed.
+	SkipReason string
+
+	// Applications is the applications discovered within the commit.
+	Applications []Application
+
+	// TypeDefs is the types defined within the commit. The shapes of those
+	// that are used as messages are recorded, such that changes to their
+	// fields can be detected.
+	TypeDefs []TypeDef
+}
+
+// SnapshotExists returns true if the given commit of a repository has already
+// been snapshotted.
+func SnapshotExists(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	commit string,
+) (bool, error) {
+	row := db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+			SELECT *
+			FROM dogmabrowser.snapshot
+			WHERE repository_id = $1
+			AND commit_hash = $2
+		)`,
+		repoID,
+		commit,
+	)
+
+	var ok bool
+	if err := row.Scan(&ok); err != nil {
+		return false, fmt.Errorf("unable to check if snapshot exists: %w", err)
+	}
+
+	return ok, nil
+}
+
+// SnapshotSkipReason returns the reason that the given commit of a repository
+// could not be analyzed, or an empty string if it was analyzed.
+//
+// The commit must already have been snapshotted.
+func SnapshotSkipReason(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	commit string,
+) (string, error) {
+	row := db.QueryRowContext(
+		ctx,
+		`SELECT skip_reason
+		FROM dogmabrowser.snapshot
+		WHERE repository_id = $1
+		AND commit_hash = $2`,
+		repoID,
+		commit,
+	)
+
+	var reason string
+	if err := row.Scan(&reason); err != nil {
+		return "", fmt.Errorf("unable to load snapshot: %w", err)
+	}
+
+	return reason, nil
+}
+
+// SaveSnapshot stores a snapshot of a commit of a repository.
+//
+// Snapshots are immutable. If the commit has already been snapshotted the
+// existing snapshot is left unchanged.
+func SaveSnapshot(
+	ctx context.Context,
+	db *sql.DB,
+	r *github.Repository,
+	s Snapshot,
+) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.snapshot (
+			repository_id,
+			commit_hash,
+			committed_at,
+			skip_reason,
+			has_message_shapes
+		) VALUES (
+			$1, $2, $3, $4, TRUE
+		) ON CONFLICT DO NOTHING`,
+		r.GetID(),
+		s.CommitHash,
+		s.CommittedAt,
+		s.SkipReason,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to save snapshot: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("unable to save snapshot: %w", err)
+	} else if n == 0 {
+		return nil
+	}
+
+	for _, a := range s.Applications {
+		if err := snapshotApplication(ctx, tx, r, s.CommitHash, a); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range s.TypeDefs {
+		if err := snapshotMessageShape(ctx, tx, r, s.CommitHash, d); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func snapshotApplication(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+	commit string,
+	a Application,
+) error {
+	pkg, name, isPointer := parseTypeName(a.Config.TypeName())
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.snapshot_application (
+			repository_id,
+			commit_hash,
+			key,
+			name,
+			type_package,
+			type_name,
+			is_pointer,
+			module_path
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		) ON CONFLICT DO NOTHING`,
+		r.GetID(),
+		commit,
+		a.Config.Identity().Key,
+		a.Config.Identity().Name,
+		pkg,
+		name,
+		isPointer,
+		a.Module,
+	); err != nil {
+		return fmt.Errorf("unable to snapshot application: %w", err)
+	}
+
+	for _, h := range a.Handlers {
+		if err := snapshotHandler(ctx, tx, r, commit, a, h); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func snapshotHandler(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+	commit string,
+	a Application,
+	h Handler,
+) error {
+	pkg, name, isPointer := parseTypeName(h.Config.TypeName())
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.snapshot_handler (
+			repository_id,
+			commit_hash,
+			key,
+			application_key,
+			name,
+			handler_type,
+			type_package,
+			type_name,
+			is_pointer
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		) ON CONFLICT DO NOTHING`,
+		r.GetID(),
+		commit,
+		h.Config.Identity().Key,
+		a.Config.Identity().Key,
+		h.Config.Identity().Name,
+		h.Config.HandlerType(),
+		pkg,
+		name,
+		isPointer,
+	); err != nil {
+		return fmt.Errorf("unable to snapshot handler: %w", err)
+	}
+
+	for _, m := range h.Messages {
+		pkg, name, isPointer := parseTypeName(m.Name)
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO dogmabrowser.snapshot_handler_message (
+				repository_id,
+				commit_hash,
+				handler_key,
+				type_package,
+				type_name,
+				is_pointer,
+				kind,
+				is_produced,
+				is_consumed
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9
+			) ON CONFLICT DO NOTHING`,
+			r.GetID(),
+			commit,
+			h.Config.Identity().Key,
+			pkg,
+			name,
+			isPointer,
+			m.Kind,
+			m.IsProduced,
+			m.IsConsumed,
+		); err != nil {
+			return fmt.Errorf("unable to snapshot message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// snapshotMessageShape records the fields of the given struct type, if it is
+// used as a message, either by the applications within the snapshot or by any
+// application that is currently known to the browser.
+func snapshotMessageShape(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+	commit string,
+	d TypeDef,
+) error {
+	if d.Fields == nil {
+		return nil
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.snapshot_message_type (
+			repository_id,
+			commit_hash,
+			type_package,
+			type_name
+		)
+		SELECT $1, $2, $3, $4
+		WHERE EXISTS (
+			SELECT *
+			FROM dogmabrowser.snapshot_handler_message AS m
+			WHERE m.repository_id = $1
+			AND m.commit_hash = $2
+			AND m.type_package = $3
+			AND m.type_name = $4
+		) OR EXISTS (
+			SELECT *
+			FROM dogmabrowser.handler_message AS m
+			INNER JOIN dogmabrowser.type AS t
+			ON t.id = m.type_id
+			WHERE t.package = $3
+			AND t.name = $4
+		)
+		ON CONFLICT DO NOTHING`,
+		r.GetID(),
+		commit,
+		d.Package,
+		d.Name,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to snapshot message type: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); n == 0 || err != nil {
+		return err
+	}
+
+	for i, f := range d.Fields {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO dogmabrowser.snapshot_message_field (
+				repository_id,
+				commit_hash,
+				type_package,
+				type_name,
+				position,
+				name,
+				field_type,
+				tag
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8
+			)`,
+			r.GetID(),
+			commit,
+			d.Package,
+			d.Name,
+			i,
+			f.Name,
+			f.Type,
+			f.Tag,
+		); err != nil {
+			return fmt.Errorf("unable to snapshot message field: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -8,15 +8,57 @@ import (
 	"path"
 	"strings"
 
+	"github.com/dogmatiq/browser/typeexpr"
 	"github.com/google/go-github/v38/github"
 )
 
 type TypeDef struct {
+	Module  string
 	Package string
 	Name    string
 	File    string
 	Line    int
 	Docs    string
+
+	// Fields is the fields of the type, or nil if it is not a struct.
+	Fields []Field
+}
+
+// Field is a field of a struct type.
+type Field struct {
+	// Name is the name of the field. The name of an embedded field is the name
+	// of its type.
+	Name string
+
+	// Type is the fully-qualified name of the field's type.
+	Type string
+
+	// Tag is the field's struct tag, if any.
+	Tag string
+
+	// Docs is the documentation comment of the field, or its line comment if
+	// it has no documentation.
+	Docs string
+
+	// IsEmbedded is true if the field is embedded within the struct.
+	IsEmbedded bool
+}
+
+// parseTypeName splits a fully-qualified type name, as produced by configkit,
+// into the package path and name that identify the type.
+//
+// isPointer is true if n is a pointer type, in which case pkg and name identify
+// the pointer's element type.
+func parseTypeName(n string) (pkg, name string, isPointer bool) {
+	e := typeexpr.Parse(n)
+
+	if e.Kind == typeexpr.Pointer {
+		isPointer = true
+		e = *e.Elem
+	}
+
+	pkg, name = e.Split()
+	return pkg, name, isPointer
 }
 
 func syncTypeRef(
@@ -24,38 +66,131 @@ func syncTypeRef(
 	tx *sql.Tx,
 	name string,
 ) (typeID int, isPointer bool, err error) {
-	if name[0] == '*' {
+	e := typeexpr.Parse(name)
+
+	if e.Kind == typeexpr.Pointer {
 		isPointer = true
-		name = name[1:]
+		e = *e.Elem
+	}
+
+	typeID, err = syncTypeExpr(ctx, tx, e)
+	return typeID, isPointer, err
+}
+
+// syncTypeExpr stores the structure of a type expression, including each of
+// the types that it refers to, and returns the ID of the type.
+//
+// An instantiated generic type refers to its generic origin type, from which
+// it inherits its source URL and documentation.
+func syncTypeExpr(
+	ctx context.Context,
+	tx *sql.Tx,
+	e typeexpr.Expr,
+) (int, error) {
+	var (
+		originID, elemID, keyID sql.NullInt64
+		argIDs                  []int
+	)
+
+	if e.Kind == typeexpr.Named && len(e.Args) > 0 {
+		id, err := syncTypeExpr(ctx, tx, e.Origin())
+		if err != nil {
+			return 0, err
+		}
+		originID = sql.NullInt64{Int64: int64(id), Valid: true}
+
+		for _, a := range e.Args {
+			id, err := syncTypeExpr(ctx, tx, a)
+			if err != nil {
+				return 0, err
+			}
+			argIDs = append(argIDs, id)
+		}
 	}
 
-	var pkg string
-	if n := strings.LastIndexByte(name, '.'); n != -1 {
-		pkg = name[:n]
-		name = name[n+1:]
+	if e.Elem != nil {
+		id, err := syncTypeExpr(ctx, tx, *e.Elem)
+		if err != nil {
+			return 0, err
+		}
+		elemID = sql.NullInt64{Int64: int64(id), Valid: true}
 	}
 
+	if e.Key != nil {
+		id, err := syncTypeExpr(ctx, tx, *e.Key)
+		if err != nil {
+			return 0, err
+		}
+		keyID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+
+	pkg, name := e.Split()
+
 	row := tx.QueryRowContext(
 		ctx,
 		`INSERT INTO dogmabrowser.type (
 			package,
-			name
+			name,
+			kind,
+			origin_id,
+			elem_id,
+			key_id,
+			length,
+			url,
+			docs
 		) VALUES (
-			$1, $2
+			$1, $2, $3, $4, $5, $6, $7,
+			(SELECT url FROM dogmabrowser.type WHERE id = $4),
+			(SELECT docs FROM dogmabrowser.type WHERE id = $4)
 		) ON CONFLICT (package, name) DO UPDATE SET
-			package = excluded.package
-		RETURNING id`, // DO UPDATE is a no-op that allows use of RETURNING when row already exists
+			kind = excluded.kind,
+			origin_id = excluded.origin_id,
+			elem_id = excluded.elem_id,
+			key_id = excluded.key_id,
+			length = excluded.length
+		RETURNING id`,
 		pkg,
 		name,
+		e.Kind,
+		originID,
+		elemID,
+		keyID,
+		e.Len,
 	)
 
+	var typeID int
 	if err := row.Scan(&typeID); err != nil {
-		return 0, false, fmt.Errorf("unable to sync type reference: %w", err)
+		return 0, fmt.Errorf("unable to sync type reference: %w", err)
 	}
 
-	return typeID, isPointer, nil
+	for i, id := range argIDs {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO dogmabrowser.type_argument (
+				type_id,
+				position,
+				argument_id
+			) VALUES (
+				$1, $2, $3
+			) ON CONFLICT (type_id, position) DO UPDATE SET
+				argument_id = excluded.argument_id`,
+			typeID,
+			i,
+			id,
+		); err != nil {
+			return 0, fmt.Errorf("unable to sync type argument: %w", err)
+		}
+	}
+
+	return typeID, nil
 }
 
+// syncTypeDefs replaces the type definitions discovered within the repository.
+//
+// The same type may be defined by more than one repository, such as when a
+// package is vendored, forked or copied. Each definition is recorded as a
+// separate source, and the type refers to its canonical source, as chosen by
+// chooseCanonicalTypeSources().
 func syncTypeDefs(
 	ctx context.Context,
 	tx *sql.Tx,
@@ -73,6 +208,15 @@ func syncTypeDefs(
 		return fmt.Errorf("unable to mark types for removal: %w", err)
 	}
 
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.type_source
+		WHERE repository_id = $1`,
+		r.GetID(),
+	); err != nil {
+		return fmt.Errorf("unable to remove type sources: %w", err)
+	}
+
 	for _, t := range defs {
 		if err := syncTypeDef(
 			ctx,
@@ -85,6 +229,10 @@ func syncTypeDefs(
 		}
 	}
 
+	if err := chooseCanonicalTypeSources(ctx, tx, r.GetID()); err != nil {
+		return err
+	}
+
 	if _, err := tx.ExecContext(
 		ctx,
 		`DELETE FROM dogmabrowser.type AS t
@@ -92,7 +240,9 @@ func syncTypeDefs(
 		AND needs_removal
 		AND NOT EXISTS (SELECT * FROM dogmabrowser.application WHERE type_id = t.id)
 		AND NOT EXISTS (SELECT * FROM dogmabrowser.handler WHERE type_id = t.id)
-		AND NOT EXISTS (SELECT * FROM dogmabrowser.handler_message WHERE type_id = t.id)`,
+		AND NOT EXISTS (SELECT * FROM dogmabrowser.handler_message WHERE type_id = t.id)
+		AND NOT EXISTS (SELECT * FROM dogmabrowser.type_argument WHERE argument_id = t.id)
+		AND NOT EXISTS (SELECT * FROM dogmabrowser.type WHERE t.id IN (origin_id, elem_id, key_id))`,
 		r.GetID(),
 	); err != nil {
 		return fmt.Errorf("unable to remove types: %w", err)
@@ -124,28 +274,184 @@ func syncTypeDef(
 
 	u.Fragment = fmt.Sprintf("L%d", t.Line)
 
-	if _, err = tx.ExecContext(
+	row := tx.QueryRowContext(
 		ctx,
 		`INSERT INTO dogmabrowser.type (
 			package,
-			name,
+			name
+		) VALUES (
+			$1, $2
+		) ON CONFLICT (package, name) DO UPDATE SET
+			package = excluded.package
+		RETURNING id`, // DO UPDATE is a no-op that allows use of RETURNING when row already exists
+		t.Package,
+		t.Name,
+	)
+
+	var typeID int
+	if err := row.Scan(&typeID); err != nil {
+		return fmt.Errorf("unable to sync type definition: %w", err)
+	}
+
+	// If the repository defines the same type more than once, such as within
+	// several modules, a definition from the module that owns the package is
+	// preferred.
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.type_source AS s (
+			type_id,
 			repository_id,
+			module_path,
 			url,
-			docs
+			docs,
+			is_owner
 		) VALUES (
-			$1, $2, $3, $4, $5
-		) ON CONFLICT (package, name) DO UPDATE SET
-			repository_id = excluded.repository_id,
+			$1, $2, $3, $4, $5, $6
+		) ON CONFLICT (type_id, repository_id) DO UPDATE SET
+			module_path = excluded.module_path,
 			url = excluded.url,
 			docs = excluded.docs,
-			needs_removal = FALSE`,
-		t.Package,
-		t.Name,
+			is_owner = excluded.is_owner
+		WHERE excluded.is_owner OR NOT s.is_owner`,
+		typeID,
 		r.GetID(),
+		t.Module,
 		u.String(),
 		t.Docs,
+		ownsPackage(t.Module, t.Package),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to sync type source: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); n == 0 || err != nil {
+		return err
+	}
+
+	return syncFields(ctx, tx, r, typeID, t.Fields)
+}
+
+// syncFields replaces the fields of the struct type with the given ID, as
+// defined within the repository.
+func syncFields(
+	ctx context.Context,
+	tx *sql.Tx,
+	r *github.Repository,
+	typeID int,
+	fields []Field,
+) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dogmabrowser.type_field
+		WHERE type_id = $1
+		AND repository_id = $2`,
+		typeID,
+		r.GetID(),
 	); err != nil {
-		return fmt.Errorf("unable to sync type definition: %w", err)
+		return fmt.Errorf("unable to remove fields: %w", err)
+	}
+
+	for i, f := range fields {
+		pkg, name, isPointer := parseTypeName(f.Type)
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO dogmabrowser.type_field (
+				type_id,
+				repository_id,
+				position,
+				name,
+				type_package,
+				type_name,
+				is_pointer,
+				tag,
+				docs,
+				is_embedded
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+			)`,
+			typeID,
+			r.GetID(),
+			i,
+			f.Name,
+			pkg,
+			name,
+			isPointer,
+			f.Tag,
+			f.Docs,
+			f.IsEmbedded,
+		); err != nil {
+			return fmt.Errorf("unable to sync field: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ownsPackage returns true if the package with the given path belongs to the
+// module with the given path, as opposed to being a copy of a package from
+// some other module.
+func ownsPackage(mod, pkg string) bool {
+	if mod == "" {
+		return false
+	}
+
+	return pkg == mod || strings.HasPrefix(pkg, mod+"/")
+}
+
+// chooseCanonicalTypeSources updates each type that is, or was, defined by
+// the repository with the given ID, or that has no canonical source, to refer
+// to its canonical source.
+//
+// A source within the module that owns the type's package is preferred over
+// copies of the package. Otherwise, the source within the oldest repository is
+// preferred, such that the choice does not depend on the order in which the
+// repositories are analyzed.
+func chooseCanonicalTypeSources(
+	ctx context.Context,
+	tx *sql.Tx,
+	repoID int64,
+) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.type AS t SET
+			repository_id = s.repository_id,
+			module_path = s.module_path,
+			url = s.url,
+			docs = s.docs,
+			needs_removal = FALSE
+		FROM (
+			SELECT DISTINCT ON (x.type_id) *
+			FROM dogmabrowser.type_source AS x
+			ORDER BY x.type_id, x.is_owner DESC, x.repository_id
+		) AS s
+		WHERE s.type_id = t.id
+		AND (
+			t.repository_id IS NULL
+			OR t.repository_id = $1
+			OR EXISTS (
+				SELECT *
+				FROM dogmabrowser.type_source AS y
+				WHERE y.type_id = t.id
+				AND y.repository_id = $1
+			)
+		)`,
+		repoID,
+	); err != nil {
+		return fmt.Errorf("unable to choose canonical type sources: %w", err)
+	}
+
+	// Instantiations of generic types share the source of their origin type.
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE dogmabrowser.type AS t SET
+			url = o.url,
+			docs = o.docs
+		FROM dogmabrowser.type AS o
+		WHERE o.id = t.origin_id
+		AND (t.url, t.docs) IS DISTINCT FROM (o.url, o.docs)`,
+	); err != nil {
+		return fmt.Errorf("unable to update generic type instantiations: %w", err)
 	}
 
 	return nil
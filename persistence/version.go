@@ -0,0 +1,91 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/go-github/v38/github"
+)
+
+// Version is a tagged version of a repository.
+type Version struct {
+	// Tag is the name of the Git tag.
+	Tag string
+
+	// CommitHash is the hash of the commit that the tag refers to.
+	CommitHash string
+
+	// IsRelease is true if the tag is associated with a published GitHub
+	// release.
+	IsRelease bool
+
+	// ReleaseName is the name of the GitHub release, if any.
+	ReleaseName string
+}
+
+// SnapshottedVersions returns the tags of the repository's versions that have
+// already been analyzed.
+func SnapshottedVersions(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+) (map[string]bool, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT tag
+		FROM dogmabrowser.version
+		WHERE repository_id = $1`,
+		repoID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query versions: %w", err)
+	}
+	defer rows.Close()
+
+	tags := map[string]bool{}
+
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("unable to scan version: %w", err)
+		}
+
+		tags[tag] = true
+	}
+
+	return tags, rows.Err()
+}
+
+// SaveVersion records a version of a repository.
+//
+// The version's commit must already have been snapshotted. Versions are
+// immutable; if the version has already been recorded it is left unchanged.
+func SaveVersion(
+	ctx context.Context,
+	db *sql.DB,
+	r *github.Repository,
+	v Version,
+) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`INSERT INTO dogmabrowser.version (
+			repository_id,
+			tag,
+			commit_hash,
+			is_release,
+			release_name
+		) VALUES (
+			$1, $2, $3, $4, $5
+		) ON CONFLICT DO NOTHING`,
+		r.GetID(),
+		v.Tag,
+		v.CommitHash,
+		v.IsRelease,
+		v.ReleaseName,
+	); err != nil {
+		return fmt.Errorf("unable to save version: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+// Package typeexpr parses the Go type expressions that name the types of
+// applications, handlers and messages, as produced by configkit.
+package typeexpr
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind is an enumeration of the kinds of type expression.
+type Kind string
+
+const (
+	// Named is a (possibly generic) named type, such as "pkg.Type" or
+	// "pkg.Type[pkg.Arg]", or a predeclared type such as "string".
+	Named Kind = "named"
+
+	// Pointer is a pointer type, such as "*pkg.Type".
+	Pointer Kind = "pointer"
+
+	// Slice is a slice type, such as "[]pkg.Type".
+	Slice Kind = "slice"
+
+	// Array is an array type, such as "[4]pkg.Type".
+	Array Kind = "array"
+
+	// Map is a map type, such as "map[string]pkg.Type".
+	Map Kind = "map"
+
+	// Opaque is a type expression that could not be parsed, such as a
+	// function, channel or anonymous struct type. Its text is stored in Name.
+	Opaque Kind = "opaque"
+)
+
+// Expr is a structured representation of a Go type expression.
+type Expr struct {
+	Kind Kind
+
+	// Package and Name are the package path and unqualified name of a Named
+	// type. Package is empty for predeclared types. Name contains the text of
+	// an Opaque type expression.
+	Package string
+	Name    string
+
+	// Args is the type arguments of an instantiated generic Named type.
+	Args []Expr
+
+	// Len is the length of an Array type, as it appears in the expression.
+	Len string
+
+	// Key is the key type of a Map type.
+	Key *Expr
+
+	// Elem is the element type of a Pointer, Slice, Array or Map type.
+	Elem *Expr
+}
+
+// Parse parses a fully-qualified type expression.
+//
+// It returns an Opaque expression if s can not be parsed.
+func Parse(s string) Expr {
+	p := &parser{s: s}
+
+	e, err := p.parse()
+	if err == nil && p.i != len(s) {
+		err = errors.New("unexpected trailing characters")
+	}
+
+	if err != nil {
+		return Expr{Kind: Opaque, Name: s}
+	}
+
+	return e
+}
+
+// Split returns the package path and name that uniquely identify the type.
+//
+// For Named types, pkg is the package path and name is the unqualified name,
+// including any type arguments. For all other kinds, pkg is empty and name is
+// the fully-qualified expression.
+func (e Expr) Split() (pkg, name string) {
+	if e.Kind != Named {
+		return "", e.String()
+	}
+
+	var w strings.Builder
+	w.WriteString(e.Name)
+	writeArgs(&w, e.Args)
+
+	return e.Package, w.String()
+}
+
+// Origin returns the generic type that e instantiates, that is, e without its
+// type arguments.
+func (e Expr) Origin() Expr {
+	return Expr{
+		Kind:    e.Kind,
+		Package: e.Package,
+		Name:    e.Name,
+	}
+}
+
+// String returns the fully-qualified type expression.
+func (e Expr) String() string {
+	var w strings.Builder
+	e.write(&w)
+	return w.String()
+}
+
+func (e Expr) write(w *strings.Builder) {
+	switch e.Kind {
+	case Named:
+		if e.Package != "" {
+			w.WriteString(e.Package)
+			w.WriteByte('.')
+		}
+		w.WriteString(e.Name)
+		writeArgs(w, e.Args)
+	case Pointer:
+		w.WriteByte('*')
+		e.Elem.write(w)
+	case Slice:
+		w.WriteString("[]")
+		e.Elem.write(w)
+	case Array:
+		w.WriteByte('[')
+		w.WriteString(e.Len)
+		w.WriteByte(']')
+		e.Elem.write(w)
+	case Map:
+		w.WriteString("map[")
+		e.Key.write(w)
+		w.WriteByte(']')
+		e.Elem.write(w)
+	default:
+		w.WriteString(e.Name)
+	}
+}
+
+func writeArgs(w *strings.Builder, args []Expr) {
+	if len(args) == 0 {
+		return
+	}
+
+	w.WriteByte('[')
+	for i, a := range args {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		a.write(w)
+	}
+	w.WriteByte(']')
+}
+
+// parser is a recursive-descent parser for type expressions.
+type parser struct {
+	s string
+	i int
+}
+
+func (p *parser) parse() (Expr, error) {
+	rest := p.s[p.i:]
+
+	switch {
+	case strings.HasPrefix(rest, "*"):
+		p.i++
+		return p.parseElem(Expr{Kind: Pointer})
+
+	case strings.HasPrefix(rest, "[]"):
+		p.i += 2
+		return p.parseElem(Expr{Kind: Slice})
+
+	case strings.HasPrefix(rest, "["):
+		n := strings.IndexByte(rest, ']')
+		if n == -1 {
+			return Expr{}, errors.New("unterminated array length")
+		}
+		p.i += n + 1
+		return p.parseElem(Expr{Kind: Array, Len: rest[1:n]})
+
+	case strings.HasPrefix(rest, "map["):
+		p.i += 4
+
+		k, err := p.parse()
+		if err != nil {
+			return Expr{}, err
+		}
+
+		if err := p.expect(']'); err != nil {
+			return Expr{}, err
+		}
+
+		return p.parseElem(Expr{Kind: Map, Key: &k})
+	}
+
+	return p.parseNamed()
+}
+
+// parseElem parses the element type of e.
+func (p *parser) parseElem(e Expr) (Expr, error) {
+	elem, err := p.parse()
+	if err != nil {
+		return Expr{}, err
+	}
+
+	e.Elem = &elem
+	return e, nil
+}
+
+// parseNamed parses a qualified type name, followed by optional type
+// arguments.
+func (p *parser) parseNamed() (Expr, error) {
+	start := p.i
+
+	for p.i < len(p.s) && !strings.ContainsRune("[], ", rune(p.s[p.i])) {
+		if strings.ContainsRune("(){}", rune(p.s[p.i])) {
+			return Expr{}, errors.New("unsupported type expression")
+		}
+		p.i++
+	}
+
+	qn := p.s[start:p.i]
+	if qn == "" {
+		return Expr{}, errors.New("expected type name")
+	}
+
+	e := Expr{Kind: Named, Name: qn}
+
+	// The package path may itself contain dots, so the unqualified name begins
+	// after the last dot that follows the last slash.
+	if n := strings.LastIndexByte(qn, '.'); n != -1 && n > strings.LastIndexByte(qn, '/') {
+		e.Package = qn[:n]
+		e.Name = qn[n+1:]
+	}
+
+	if p.i == len(p.s) || p.s[p.i] != '[' {
+		return e, nil
+	}
+
+	p.i++
+
+	for {
+		a, err := p.parse()
+		if err != nil {
+			return Expr{}, err
+		}
+		e.Args = append(e.Args, a)
+
+		if p.i < len(p.s) && p.s[p.i] == ',' {
+			p.i++
+			for p.i < len(p.s) && p.s[p.i] == ' ' {
+				p.i++
+			}
+			continue
+		}
+
+		return e, p.expect(']')
+	}
+}
+
+// expect consumes the character c.
+func (p *parser) expect(c byte) error {
+	if p.i == len(p.s) || p.s[p.i] != c {
+		return errors.New("expected " + string(c))
+	}
+
+	p.i++
+	return nil
+}
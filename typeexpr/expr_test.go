@@ -0,0 +1,244 @@
+package typeexpr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func named(pkg, name string, args ...Expr) Expr {
+	return Expr{Kind: Named, Package: pkg, Name: name, Args: args}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		Name string
+		Text string
+		Want Expr
+	}{
+		{
+			"predeclared type",
+			"string",
+			named("", "string"),
+		},
+		{
+			"named type",
+			"github.com/example/pkg.Type",
+			named("github.com/example/pkg", "Type"),
+		},
+		{
+			"named type in package with dots in final path element",
+			"gopkg.in/yaml.v3.Node",
+			named("gopkg.in/yaml.v3", "Node"),
+		},
+		{
+			"pointer",
+			"*github.com/example/pkg.Type",
+			Expr{
+				Kind: Pointer,
+				Elem: &Expr{Kind: Named, Package: "github.com/example/pkg", Name: "Type"},
+			},
+		},
+		{
+			"slice",
+			"[]github.com/example/pkg.Type",
+			Expr{
+				Kind: Slice,
+				Elem: &Expr{Kind: Named, Package: "github.com/example/pkg", Name: "Type"},
+			},
+		},
+		{
+			"array",
+			"[4]github.com/example/pkg.Type",
+			Expr{
+				Kind: Array,
+				Len:  "4",
+				Elem: &Expr{Kind: Named, Package: "github.com/example/pkg", Name: "Type"},
+			},
+		},
+		{
+			"slice of pointers",
+			"[]*github.com/example/pkg.Type",
+			Expr{
+				Kind: Slice,
+				Elem: &Expr{
+					Kind: Pointer,
+					Elem: &Expr{Kind: Named, Package: "github.com/example/pkg", Name: "Type"},
+				},
+			},
+		},
+		{
+			"map",
+			"map[string]github.com/example/pkg.Type",
+			Expr{
+				Kind: Map,
+				Key:  &Expr{Kind: Named, Name: "string"},
+				Elem: &Expr{Kind: Named, Package: "github.com/example/pkg", Name: "Type"},
+			},
+		},
+		{
+			"map with composite key and element types",
+			"map[[2]int][]*gopkg.in/yaml.v3.Node",
+			Expr{
+				Kind: Map,
+				Key: &Expr{
+					Kind: Array,
+					Len:  "2",
+					Elem: &Expr{Kind: Named, Name: "int"},
+				},
+				Elem: &Expr{
+					Kind: Slice,
+					Elem: &Expr{
+						Kind: Pointer,
+						Elem: &Expr{Kind: Named, Package: "gopkg.in/yaml.v3", Name: "Node"},
+					},
+				},
+			},
+		},
+		{
+			"generic instantiation",
+			"github.com/example/pkg.Envelope[github.com/other/pkg.Payload]",
+			named(
+				"github.com/example/pkg",
+				"Envelope",
+				named("github.com/other/pkg", "Payload"),
+			),
+		},
+		{
+			"nested generic instantiation",
+			"github.com/example/pkg.Envelope[github.com/other/pkg.Wrapper[gopkg.in/yaml.v3.Node], map[string]*github.com/example/pkg.Type]",
+			named(
+				"github.com/example/pkg",
+				"Envelope",
+				named(
+					"github.com/other/pkg",
+					"Wrapper",
+					named("gopkg.in/yaml.v3", "Node"),
+				),
+				Expr{
+					Kind: Map,
+					Key:  &Expr{Kind: Named, Name: "string"},
+					Elem: &Expr{
+						Kind: Pointer,
+						Elem: &Expr{Kind: Named, Package: "github.com/example/pkg", Name: "Type"},
+					},
+				},
+			),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			got := Parse(c.Text)
+
+			if !reflect.DeepEqual(got, c.Want) {
+				t.Fatalf("unexpected expression:\n got: %#v\nwant: %#v", got, c.Want)
+			}
+
+			if s := got.String(); s != c.Text {
+				t.Fatalf("unexpected string: got %q, want %q", s, c.Text)
+			}
+		})
+	}
+}
+
+func TestParse_malformed(t *testing.T) {
+	cases := []struct {
+		Name string
+		Text string
+	}{
+		{"empty", ""},
+		{"unterminated type arguments", "github.com/example/pkg.Type["},
+		{"empty type arguments", "github.com/example/pkg.Type[]"},
+		{"unterminated map key", "map[string"},
+		{"missing map element", "map[string]"},
+		{"unterminated array length", "[4"},
+		{"missing pointer element", "*"},
+		{"trailing bracket", "github.com/example/pkg.Type]"},
+		{"function", "func(int) error"},
+		{"channel", "chan int"},
+		{"anonymous struct", "struct{}"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			got := Parse(c.Text)
+			want := Expr{Kind: Opaque, Name: c.Text}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("unexpected expression:\n got: %#v\nwant: %#v", got, want)
+			}
+
+			if s := got.String(); s != c.Text {
+				t.Fatalf("unexpected string: got %q, want %q", s, c.Text)
+			}
+		})
+	}
+}
+
+func TestExpr_Split(t *testing.T) {
+	cases := []struct {
+		Text     string
+		WantPkg  string
+		WantName string
+	}{
+		{"string", "", "string"},
+		{"github.com/example/pkg.Type", "github.com/example/pkg", "Type"},
+		{"gopkg.in/yaml.v3.Node", "gopkg.in/yaml.v3", "Node"},
+		{
+			"github.com/example/pkg.Envelope[github.com/other/pkg.Wrapper[gopkg.in/yaml.v3.Node], map[string]*github.com/example/pkg.Type]",
+			"github.com/example/pkg",
+			"Envelope[github.com/other/pkg.Wrapper[gopkg.in/yaml.v3.Node], map[string]*github.com/example/pkg.Type]",
+		},
+		{"*github.com/example/pkg.Type", "", "*github.com/example/pkg.Type"},
+		{"[]github.com/example/pkg.Type", "", "[]github.com/example/pkg.Type"},
+		{"[4]github.com/example/pkg.Type", "", "[4]github.com/example/pkg.Type"},
+		{"map[string]github.com/example/pkg.Type", "", "map[string]github.com/example/pkg.Type"},
+		{"func(int) error", "", "func(int) error"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Text, func(t *testing.T) {
+			pkg, name := Parse(c.Text).Split()
+
+			if pkg != c.WantPkg || name != c.WantName {
+				t.Fatalf(
+					"unexpected result: got (%q, %q), want (%q, %q)",
+					pkg, name,
+					c.WantPkg, c.WantName,
+				)
+			}
+		})
+	}
+}
+
+func TestExpr_Origin(t *testing.T) {
+	cases := []struct {
+		Text string
+		Want string
+	}{
+		{"string", "string"},
+		{"github.com/example/pkg.Type", "github.com/example/pkg.Type"},
+		{
+			"github.com/example/pkg.Envelope[github.com/other/pkg.Payload]",
+			"github.com/example/pkg.Envelope",
+		},
+		{
+			"gopkg.in/yaml.v3.Wrapper[github.com/other/pkg.Wrapper[gopkg.in/yaml.v3.Node], []int]",
+			"gopkg.in/yaml.v3.Wrapper",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Text, func(t *testing.T) {
+			got := Parse(c.Text).Origin()
+
+			if got.Args != nil {
+				t.Fatalf("unexpected type arguments: %#v", got.Args)
+			}
+
+			if s := got.String(); s != c.Want {
+				t.Fatalf("unexpected origin: got %q, want %q", s, c.Want)
+			}
+		})
+	}
+}
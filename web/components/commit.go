@@ -0,0 +1,25 @@
+package components
+
+type Commit struct {
+	RepoURL string
+	Hash    string
+}
+
+// URL returns the URL of the commit on GitHub, or an empty string if the
+// repository's URL is unknown.
+func (c Commit) URL() string {
+	if c.RepoURL == "" {
+		return ""
+	}
+
+	return c.RepoURL + "/commit/" + c.Hash
+}
+
+// ShortHash returns the abbreviated commit hash.
+func (c Commit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+
+	return c.Hash
+}
@@ -6,4 +6,9 @@ const (
 	ApplicationsMenuItem MenuItem = "applications"
 	HandlersMenuItem     MenuItem = "handlers"
 	MessagesMenuItem     MenuItem = "messages"
+	RepositoriesMenuItem MenuItem = "repositories"
+	FindingsMenuItem     MenuItem = "findings"
+	QueueMenuItem        MenuItem = "queue"
+	RunsMenuItem         MenuItem = "runs"
+	DeliveriesMenuItem   MenuItem = "deliveries"
 )
@@ -1,5 +1,7 @@
 package components
 
+import "github.com/dogmatiq/browser/typeexpr"
+
 type Type struct {
 	Package   string
 	Name      string
@@ -7,3 +9,15 @@ type Type struct {
 	URL       string
 	Docs      string
 }
+
+// Expr returns the structure of the type expression.
+//
+// The types that appear within the expression, such as the type arguments of
+// a generic type, are rendered as links to their own source.
+func (t Type) Expr() typeexpr.Expr {
+	if t.Package == "" {
+		return typeexpr.Parse(t.Name)
+	}
+
+	return typeexpr.Parse(t.Package + "." + t.Name)
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dogmatiq/browser/analyzer"
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v38/github"
+)
+
+func replayDelivery(version string, o *analyzer.Orchestrator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+
+		d, ok, err := persistence.LoadDelivery(ctx, o.DB, id)
+		if err != nil {
+			fmt.Println("unable to load delivery:", err) // TODO
+			renderError(ctx, version, http.StatusInternalServerError)
+			return
+		}
+
+		if !ok {
+			renderError(ctx, version, http.StatusNotFound)
+			return
+		}
+
+		event, err := github.ParseWebHook(d.Event, d.Payload)
+		if err != nil {
+			fmt.Println("unable to parse delivery:", err) // TODO
+			renderError(ctx, version, http.StatusInternalServerError)
+			return
+		}
+
+		// Failures are recorded against the delivery, and so are shown on the
+		// delivery's page rather than as an error.
+		if err := processDelivery(ctx, o, d.ID, event); err != nil {
+			fmt.Println("unable to replay delivery:", err) // TODO
+		}
+
+		ctx.Redirect(http.StatusSeeOther, "/deliveries/"+d.ID)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/dogmatiq/browser/analyzer"
+	"github.com/dogmatiq/browser/persistence"
 	"github.com/gin-gonic/gin"
 	"github.com/google/go-github/v38/github"
 )
@@ -31,7 +33,26 @@ func handleGitHubHook(
 			return
 		}
 
-		if err := handleGitHubEvent(ctx, o, event); err != nil {
+		d := newDelivery(github.DeliveryID(ctx.Request), hookType, payload, event)
+
+		// GitHub may deliver the same event more than once. Deliveries that
+		// have already been processed successfully are acknowledged without
+		// being processed again.
+		if d.ID != "" {
+			ok, err := persistence.RecordDelivery(ctx, o.DB, d)
+			if err != nil {
+				renderError(ctx, version, http.StatusInternalServerError)
+				fmt.Println("unable to record delivery", err) // TODO
+				return
+			}
+
+			if !ok {
+				ctx.Writer.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if err := processDelivery(ctx, o, d.ID, event); err != nil {
 			renderError(ctx, version, http.StatusInternalServerError)
 			fmt.Println("unable to handle event", err) // TODO
 			return
@@ -41,12 +62,56 @@ func handleGitHubHook(
 	}
 }
 
+// newDelivery returns a record of a webhook delivery.
+func newDelivery(
+	id, hookType string,
+	payload []byte,
+	event interface{},
+) persistence.Delivery {
+	d := persistence.Delivery{
+		ID:      id,
+		Event:   hookType,
+		Payload: payload,
+	}
+
+	if e, ok := event.(interface{ GetAction() string }); ok {
+		d.Action = e.GetAction()
+	}
+
+	if e, ok := event.(interface{ GetRepo() *github.Repository }); ok {
+		d.RepositoryID = e.GetRepo().GetID()
+		d.FullName = e.GetRepo().GetFullName()
+	}
+
+	return d
+}
+
+// processDelivery handles a webhook event and records the outcome against the
+// delivery with the given ID.
+//
+// If id is empty the outcome is not recorded.
+func processDelivery(
+	ctx context.Context,
+	o *analyzer.Orchestrator,
+	id string,
+	event interface{},
+) error {
+	err := handleGitHubEvent(ctx, o, event)
+
+	if id != "" {
+		if e := persistence.CompleteDelivery(ctx, o.DB, id, err); e != nil && err == nil {
+			return e
+		}
+	}
+
+	return err
+}
+
 func handleGitHubEvent(
 	ctx context.Context,
 	o *analyzer.Orchestrator,
 	event interface{},
 ) error {
-	// TODO: handle repository rename
 	switch event := event.(type) {
 	case *github.InstallationEvent:
 		return handleInstallationEvent(ctx, o, event)
@@ -54,6 +119,12 @@ func handleGitHubEvent(
 		return handleInstallationRepositoriesEvent(ctx, o, event)
 	case *github.PushEvent:
 		return handlePushEvent(ctx, o, event)
+	case *github.ReleaseEvent:
+		return handleReleaseEvent(ctx, o, event)
+	case *github.PullRequestEvent:
+		return handlePullRequestEvent(ctx, o, event)
+	case *github.RepositoryEvent:
+		return handleRepositoryEvent(ctx, o, event)
 	}
 
 	return nil
@@ -71,13 +142,21 @@ func handleInstallationEvent(
 	switch *event.Action {
 	case "created", "unsuspend", "new_permissions_accepted":
 		for _, r := range event.Repositories {
-			if err := o.EnqueueAnalyis(ctx, r.GetID()); err != nil {
+			if err := o.EnqueueAnalyis(
+				ctx,
+				r.GetID(),
+				persistence.InstallationTrigger,
+			); err != nil {
 				return err
 			}
 		}
 	case "deleted", "suspend":
 		for _, r := range event.Repositories {
-			if err := o.EnqueueRemoval(ctx, r.GetID()); err != nil {
+			if err := o.EnqueueRemoval(
+				ctx,
+				r.GetID(),
+				persistence.InstallationTrigger,
+			); err != nil {
 				return err
 			}
 		}
@@ -92,13 +171,21 @@ func handleInstallationRepositoriesEvent(
 	event *github.InstallationRepositoriesEvent,
 ) error {
 	for _, r := range event.RepositoriesRemoved {
-		if err := o.EnqueueRemoval(ctx, r.GetID()); err != nil {
+		if err := o.EnqueueRemoval(
+			ctx,
+			r.GetID(),
+			persistence.InstallationTrigger,
+		); err != nil {
 			return err
 		}
 	}
 
 	for _, r := range event.RepositoriesAdded {
-		if err := o.EnqueueAnalyis(ctx, r.GetID()); err != nil {
+		if err := o.EnqueueAnalyis(
+			ctx,
+			r.GetID(),
+			persistence.InstallationTrigger,
+		); err != nil {
 			return err
 		}
 	}
@@ -114,9 +201,142 @@ func handlePushEvent(
 	repo := event.GetRepo()
 	defaultRef := "refs/heads/" + repo.GetDefaultBranch()
 
-	if event.GetRef() != defaultRef {
+	// Pushes of new tags are analyzed so that a snapshot of the version can be
+	// taken. Tags are immutable once snapshotted, so there is nothing to do
+	// when a tag is deleted.
+	isTag := strings.HasPrefix(event.GetRef(), "refs/tags/") && !event.GetDeleted()
+
+	if event.GetRef() != defaultRef && !isTag {
+		// Any other ref only needs to be analyzed if it is deployed to one of
+		// the repository's environments, in which case the environment may now
+		// refer to a different commit, or to a different ref altogether.
+		ok, err := persistence.IsEnvironmentRef(
+			ctx,
+			o.DB,
+			repo.GetID(),
+			event.GetRef(),
+		)
+		if !ok || err != nil {
+			return err
+		}
+	}
+
+	return o.EnqueueAnalyis(
+		ctx,
+		repo.GetID(),
+		persistence.PushTrigger,
+	)
+}
+
+func handleReleaseEvent(
+	ctx context.Context,
+	o *analyzer.Orchestrator,
+	event *github.ReleaseEvent,
+) error {
+	if event.GetAction() != "published" {
+		return nil
+	}
+
+	return o.EnqueueAnalyis(
+		ctx,
+		event.GetRepo().GetID(),
+		persistence.ReleaseTrigger,
+	)
+}
+
+func handlePullRequestEvent(
+	ctx context.Context,
+	o *analyzer.Orchestrator,
+	event *github.PullRequestEvent,
+) error {
+	repo := event.GetRepo()
+	pr := event.GetPullRequest()
+
+	switch event.GetAction() {
+	case "opened", "reopened", "synchronize":
+	case "edited":
+		// Edits to the title or body of the pull request do not affect the
+		// analysis, only changes to the base branch.
+		if event.GetChanges().GetBase() == nil {
+			return nil
+		}
+	case "closed":
+		return persistence.RemovePullRequest(
+			ctx,
+			o.DB,
+			repo.GetID(),
+			pr.GetNumber(),
+		)
+	default:
 		return nil
 	}
 
-	return o.EnqueueAnalyis(ctx, repo.GetID())
+	if err := persistence.SavePullRequest(
+		ctx,
+		o.DB,
+		repo.GetID(),
+		persistence.PullRequest{
+			Number:         pr.GetNumber(),
+			HeadCommitHash: pr.GetHead().GetSHA(),
+			BaseRef:        pr.GetBase().GetRef(),
+			BaseCommitHash: pr.GetBase().GetSHA(),
+		},
+	); err != nil {
+		return err
+	}
+
+	return o.EnqueueAnalyis(
+		ctx,
+		repo.GetID(),
+		persistence.PullRequestTrigger,
+	)
+}
+
+func handleRepositoryEvent(
+	ctx context.Context,
+	o *analyzer.Orchestrator,
+	event *github.RepositoryEvent,
+) error {
+	repo := event.GetRepo()
+
+	switch event.GetAction() {
+	case "renamed":
+		// The repository's content is unchanged, so there is no need to
+		// analyze it again, only to update the URLs that refer to it.
+		return persistence.RenameRepository(ctx, o.DB, repo)
+
+	case "transferred":
+		// The repository may no longer be accessible to the installation after
+		// being transferred to a new owner, so it is analyzed again to confirm
+		// that it is still available.
+		if err := persistence.RenameRepository(ctx, o.DB, repo); err != nil {
+			return err
+		}
+
+		return o.EnqueueAnalyis(ctx, repo.GetID(), persistence.RepositoryTrigger)
+
+	case "archived", "unarchived":
+		// Archived repositories are skipped by the analyzer. The repository is
+		// marked as stale so that its default branch is analyzed again even
+		// though its commit has not changed, which removes (or restores) its
+		// applications.
+		if err := persistence.MarkRepositoryStale(ctx, o.DB, repo.GetID()); err != nil {
+			return err
+		}
+
+		return o.EnqueueAnalyis(ctx, repo.GetID(), persistence.RepositoryTrigger)
+
+	case "privatized", "publicized":
+		return persistence.SetRepositoryVisibility(
+			ctx,
+			o.DB,
+			repo.GetID(),
+			repo.GetPrivate(),
+		)
+
+	case "deleted":
+		return o.EnqueueRemoval(ctx, repo.GetID(), persistence.RepositoryTrigger)
+	}
+
+	return nil
 }
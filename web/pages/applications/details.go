@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/dogmatiq/browser/persistence"
 	"github.com/dogmatiq/browser/web/components"
 	"github.com/dogmatiq/configkit"
 	"github.com/dogmatiq/configkit/message"
@@ -13,13 +16,35 @@ import (
 
 // detailsView is the template context for details.html.
 type detailsView struct {
-	Key  string
-	Name string
-	Impl components.Type
+	Key      string
+	Name     string
+	Impl     components.Type
+	RepoID   int64
+	RepoName string
+	Module   string
+	Builds   []string
+
+	// HasEnvironments is true if the application's repository defines any
+	// deployment environments.
+	HasEnvironments bool
+
+	// Collisions is the other applications that declare the same identity key,
+	// within this or other repositories.
+	Collisions []collision
 
 	Relationships []relationship
 	Handlers      []handlerSummary
 	Messages      []messageSummary
+	Versions      []versionSummary
+}
+
+// collision is another declaration of an application's identity key, for
+// display within a detailsView.
+type collision struct {
+	Name     string
+	Impl     components.Type
+	RepoID   int64
+	RepoName string
 }
 
 // relationship contains a summary of information about an application that is
@@ -55,6 +80,16 @@ type messageSummary struct {
 	ConsumerCount int
 }
 
+// versionSummary contains a summary of information about a version of the
+// application, for display within a detailsView.
+type versionSummary struct {
+	Tag          string
+	IsRelease    bool
+	Commit       components.Commit
+	CommittedAt  time.Time
+	HandlerCount int
+}
+
 // DetailsHandler is an implementation of web.Handler that displays detailed
 // information about a single Dogma application.
 type DetailsHandler struct {
@@ -87,6 +122,10 @@ func (h *DetailsHandler) View(ctx *gin.Context) (string, interface{}, error) {
 		return "", nil, err
 	}
 
+	if err := h.loadCollisions(ctx, &view, appKey); err != nil {
+		return "", nil, err
+	}
+
 	if err := h.loadRelationships(ctx, &view, appKey); err != nil {
 		return "", nil, err
 	}
@@ -99,6 +138,10 @@ func (h *DetailsHandler) View(ctx *gin.Context) (string, interface{}, error) {
 		return "", nil, err
 	}
 
+	if err := h.loadVersions(ctx, &view, appKey); err != nil {
+		return "", nil, err
+	}
+
 	return view.Name, view, nil
 }
 
@@ -116,15 +159,32 @@ func (h *DetailsHandler) loadDetails(
 			t.name,
 			a.is_pointer,
 			COALESCE(t.url, ''),
-			COALESCE(t.docs, '')
+			COALESCE(t.docs, ''),
+			r.id,
+			r.full_name,
+			a.module_path,
+			COALESCE((
+				SELECT STRING_AGG(x.build_config, E'\n' ORDER BY x.build_config)
+				FROM dogmabrowser.application_build AS x
+				WHERE x.application_key = a.key
+			), ''),
+			EXISTS (
+				SELECT *
+				FROM dogmabrowser.environment AS e
+				WHERE e.repository_id = a.repository_id
+			)
 		FROM dogmabrowser.application AS a
 		INNER JOIN dogmabrowser.type AS t
 		ON t.id = a.type_id
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = a.repository_id
 		WHERE a.key = $1`,
 		appKey,
 	)
 
-	return row.Scan(
+	var builds string
+
+	if err := row.Scan(
 		&view.Key,
 		&view.Name,
 		&view.Impl.Package,
@@ -132,7 +192,20 @@ func (h *DetailsHandler) loadDetails(
 		&view.Impl.IsPointer,
 		&view.Impl.URL,
 		&view.Impl.Docs,
-	)
+		&view.RepoID,
+		&view.RepoName,
+		&view.Module,
+		&builds,
+		&view.HasEnvironments,
+	); err != nil {
+		return err
+	}
+
+	if builds != "" {
+		view.Builds = strings.Split(builds, "\n")
+	}
+
+	return nil
 }
 
 func (h *DetailsHandler) loadRelationships(
@@ -315,7 +388,10 @@ func (h *DetailsHandler) loadMessages(
 	defer rows.Close()
 
 	for rows.Next() {
-		var s messageSummary
+		var (
+			s    messageSummary
+			kind string
+		)
 
 		if err := rows.Scan(
 			&s.Impl.Package,
@@ -323,15 +399,135 @@ func (h *DetailsHandler) loadMessages(
 			&s.Impl.IsPointer,
 			&s.Impl.URL,
 			&s.Impl.Docs,
-			&s.Kind,
+			&kind,
 			&s.ProducerCount,
 			&s.ConsumerCount,
 		); err != nil {
 			return err
 		}
 
+		if s.Kind, err = persistence.ParseKind(kind); err != nil {
+			return err
+		}
+
 		view.Messages = append(view.Messages, s)
 	}
 
 	return rows.Err()
 }
+
+func (h *DetailsHandler) loadVersions(
+	ctx context.Context,
+	view *detailsView,
+	appKey string,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			v.tag,
+			v.is_release,
+			v.commit_hash,
+			s.committed_at,
+			r.html_url,
+			(
+				SELECT COUNT(*)
+				FROM dogmabrowser.snapshot_handler AS x
+				WHERE x.repository_id = a.repository_id
+				AND x.commit_hash = a.commit_hash
+				AND x.application_key = a.key
+			) AS handler_count
+		FROM dogmabrowser.snapshot_application AS a
+		INNER JOIN dogmabrowser.snapshot AS s
+		ON s.repository_id = a.repository_id
+		AND s.commit_hash = a.commit_hash
+		INNER JOIN dogmabrowser.version AS v
+		ON v.repository_id = a.repository_id
+		AND v.commit_hash = a.commit_hash
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = a.repository_id
+		WHERE a.key = $1
+		ORDER BY s.committed_at DESC, v.tag DESC`,
+		appKey,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s versionSummary
+
+		if err := rows.Scan(
+			&s.Tag,
+			&s.IsRelease,
+			&s.Commit.Hash,
+			&s.CommittedAt,
+			&s.Commit.RepoURL,
+			&s.HandlerCount,
+		); err != nil {
+			return err
+		}
+
+		view.Versions = append(view.Versions, s)
+	}
+
+	return rows.Err()
+}
+
+func (h *DetailsHandler) loadCollisions(
+	ctx context.Context,
+	view *detailsView,
+	appKey string,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			d.name,
+			d.type_package,
+			d.type_name,
+			COALESCE(t.url, ''),
+			r.id,
+			r.full_name
+		FROM dogmabrowser.identity_declaration AS d
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = d.repository_id
+		LEFT JOIN dogmabrowser.type AS t
+		ON t.package = d.type_package
+		AND t.name = d.type_name
+		WHERE d.entity = 'application'
+		AND d.key = $1
+		AND NOT (
+			d.repository_id = $2
+			AND d.type_package = $3
+			AND d.type_name = $4
+		)
+		ORDER BY r.full_name, d.type_package, d.type_name`,
+		appKey,
+		view.RepoID,
+		view.Impl.Package,
+		view.Impl.Name,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c collision
+
+		if err := rows.Scan(
+			&c.Name,
+			&c.Impl.Package,
+			&c.Impl.Name,
+			&c.Impl.URL,
+			&c.RepoID,
+			&c.RepoName,
+		); err != nil {
+			return err
+		}
+
+		view.Collisions = append(view.Collisions, c)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,312 @@
+package applications
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/configkit/message"
+	"github.com/gin-gonic/gin"
+)
+
+// environmentsView is the template context for environments.html.
+type environmentsView struct {
+	Key      string
+	Name     string
+	RepoID   int64
+	RepoName string
+
+	Environments []environmentSummary
+	Handlers     []handlerDrift
+	Messages     []messageDrift
+
+	// IdenticalHandlerCount and IdenticalMessageCount are the number of
+	// handlers and messages that are the same in every environment, and are
+	// therefore not included in Handlers and Messages.
+	IdenticalHandlerCount int
+	IdenticalMessageCount int
+}
+
+// environmentSummary contains information about a deployment environment of
+// the application's repository.
+type environmentSummary struct {
+	Name    string
+	Pattern string
+	Ref     string
+	Commit  components.Commit
+
+	// HasApplication is true if the application is present within the commit
+	// deployed to the environment.
+	HasApplication bool
+}
+
+// handlerDrift describes a handler that is not present in every environment.
+type handlerDrift struct {
+	Key  string
+	Name string
+	Type configkit.HandlerType
+	Impl components.Type
+
+	// Present contains an element for each environment, which is true if the
+	// handler is present in that environment.
+	Present []bool
+}
+
+// messageDrift describes a message whose kind, producers or consumers differ
+// between environments.
+type messageDrift struct {
+	Impl components.Type
+
+	// Usage contains an element for each environment describing how the
+	// message is used in that environment.
+	Usage []messageUsage
+}
+
+// messageUsage describes how a message is used within a single environment.
+type messageUsage struct {
+	Present   bool
+	Kind      message.Kind
+	Producers []string
+	Consumers []string
+}
+
+// equal returns true if u and x describe the same usage.
+func (u messageUsage) equal(x messageUsage) bool {
+	return u.Present == x.Present &&
+		u.Kind == x.Kind &&
+		slices.Equal(u.Producers, x.Producers) &&
+		slices.Equal(u.Consumers, x.Consumers)
+}
+
+// EnvironmentsHandler is an implementation of web.Handler that displays the
+// differences between an application as it is deployed to each of its
+// repository's environments.
+type EnvironmentsHandler struct {
+	DB *sql.DB
+}
+
+func (h *EnvironmentsHandler) Route() (string, string) {
+	return http.MethodGet, "/applications/:key/environments"
+}
+
+func (h *EnvironmentsHandler) Template() string {
+	return "applications/environments.html"
+}
+
+func (h *EnvironmentsHandler) ActiveMenuItem() components.MenuItem {
+	return components.ApplicationsMenuItem
+}
+
+func (h *EnvironmentsHandler) View(ctx *gin.Context) (string, interface{}, error) {
+	var view environmentsView
+
+	appKey := ctx.Param("key")
+
+	commits, err := h.loadEnvironments(ctx, &view, appKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return "", nil, nil
+		}
+
+		return "", nil, err
+	}
+
+	var (
+		handlers = map[string]*handlerDrift{}
+		messages = map[components.Type]*messageDrift{}
+		hkeys    []string
+		mkeys    []components.Type
+	)
+
+	for i, commit := range commits {
+		if commit == "" {
+			continue
+		}
+
+		hs, err := loadSnapshotHandlers(ctx, h.DB, view.RepoID, commit, appKey)
+		if err != nil {
+			return "", nil, err
+		}
+
+		for _, s := range hs {
+			d, ok := handlers[s.Key]
+			if !ok {
+				d = &handlerDrift{
+					Key:     s.Key,
+					Name:    s.Name,
+					Type:    s.Type,
+					Impl:    s.Impl,
+					Present: make([]bool, len(commits)),
+				}
+				handlers[s.Key] = d
+				hkeys = append(hkeys, s.Key)
+			}
+
+			d.Present[i] = true
+		}
+
+		ms, err := loadSnapshotMessages(ctx, h.DB, view.RepoID, commit, appKey)
+		if err != nil {
+			return "", nil, err
+		}
+
+		for _, s := range ms {
+			// The pointer-ness of the message is not part of the key, such
+			// that a change from pointer to non-pointer is shown as a
+			// difference in usage, rather than as two separate messages.
+			k := components.Type{Package: s.Impl.Package, Name: s.Impl.Name}
+
+			d, ok := messages[k]
+			if !ok {
+				d = &messageDrift{
+					Impl:  s.Impl,
+					Usage: make([]messageUsage, len(commits)),
+				}
+				messages[k] = d
+				mkeys = append(mkeys, k)
+			}
+
+			d.Usage[i] = messageUsage{
+				Present:   true,
+				Kind:      s.Kind,
+				Producers: s.Producers,
+				Consumers: s.Consumers,
+			}
+		}
+	}
+
+	for _, k := range hkeys {
+		d := handlers[k]
+
+		if slices.Contains(d.Present, false) {
+			view.Handlers = append(view.Handlers, *d)
+		} else {
+			view.IdenticalHandlerCount++
+		}
+	}
+
+	for _, k := range mkeys {
+		d := messages[k]
+
+		identical := true
+		for _, u := range d.Usage[1:] {
+			if !u.equal(d.Usage[0]) {
+				identical = false
+				break
+			}
+		}
+
+		if identical {
+			view.IdenticalMessageCount++
+		} else {
+			view.Messages = append(view.Messages, *d)
+		}
+	}
+
+	slices.SortFunc(view.Handlers, func(a, b handlerDrift) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	slices.SortFunc(view.Messages, func(a, b messageDrift) int {
+		if c := strings.Compare(a.Impl.Name, b.Impl.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Impl.Package, b.Impl.Package)
+	})
+
+	return view.Name + " Environments", view, nil
+}
+
+// loadEnvironments loads the deployment environments of the repository that
+// contains the application.
+//
+// It returns the hash of the commit deployed to each environment, or an empty
+// string for environments where the application is not present.
+func (h *EnvironmentsHandler) loadEnvironments(
+	ctx context.Context,
+	view *environmentsView,
+	appKey string,
+) ([]string, error) {
+	row := h.DB.QueryRowContext(
+		ctx,
+		`SELECT
+			a.key,
+			a.name,
+			r.id,
+			r.full_name
+		FROM dogmabrowser.application AS a
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = a.repository_id
+		WHERE a.key = $1`,
+		appKey,
+	)
+
+	if err := row.Scan(
+		&view.Key,
+		&view.Name,
+		&view.RepoID,
+		&view.RepoName,
+	); err != nil {
+		return nil, err
+	}
+
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			e.name,
+			e.pattern,
+			e.ref,
+			COALESCE(e.commit_hash, ''),
+			r.html_url,
+			EXISTS (
+				SELECT *
+				FROM dogmabrowser.snapshot_application AS a
+				WHERE a.repository_id = e.repository_id
+				AND a.commit_hash = e.commit_hash
+				AND a.key = $2
+			)
+		FROM dogmabrowser.environment AS e
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = e.repository_id
+		WHERE e.repository_id = $1
+		ORDER BY e.position`,
+		view.RepoID,
+		appKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commits []string
+
+	for rows.Next() {
+		var s environmentSummary
+
+		if err := rows.Scan(
+			&s.Name,
+			&s.Pattern,
+			&s.Ref,
+			&s.Commit.Hash,
+			&s.Commit.RepoURL,
+			&s.HasApplication,
+		); err != nil {
+			return nil, err
+		}
+
+		if s.HasApplication {
+			commits = append(commits, s.Commit.Hash)
+		} else {
+			commits = append(commits, "")
+		}
+
+		view.Environments = append(view.Environments, s)
+	}
+
+	return commits, rows.Err()
+}
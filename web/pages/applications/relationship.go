@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/dogmatiq/browser/persistence"
 	"github.com/dogmatiq/browser/web/components"
 	"github.com/dogmatiq/configkit/message"
 	"github.com/gin-gonic/gin"
@@ -180,7 +181,10 @@ func (h *RelationshipHandler) loadMessages(
 	defer rows.Close()
 
 	for rows.Next() {
-		var r relatedMessage
+		var (
+			r    relatedMessage
+			kind string
+		)
 
 		if err := rows.Scan(
 			&r.Impl.Package,
@@ -188,7 +192,7 @@ func (h *RelationshipHandler) loadMessages(
 			&r.Impl.IsPointer,
 			&r.Impl.URL,
 			&r.Impl.Docs,
-			&r.Kind,
+			&kind,
 			&r.HasKindMismatch,
 			&r.HasPointerMismatch,
 			&r.ProducerCount,
@@ -197,6 +201,10 @@ func (h *RelationshipHandler) loadMessages(
 			return err
 		}
 
+		if r.Kind, err = persistence.ParseKind(kind); err != nil {
+			return err
+		}
+
 		*messages = append(*messages, r)
 	}
 
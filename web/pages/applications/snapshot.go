@@ -0,0 +1,187 @@
+package applications
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/configkit/message"
+)
+
+// snapshotHandler contains a summary of information about a handler within an
+// application, as it was at a specific commit.
+type snapshotHandler struct {
+	Key                  string
+	Name                 string
+	Type                 configkit.HandlerType
+	Impl                 components.Type
+	ConsumedMessageCount int
+	ProducedMessageCount int
+	IsNew                bool
+}
+
+// snapshotMessage contains a summary of information about a message used by
+// an application, as it was at a specific commit.
+type snapshotMessage struct {
+	Impl      components.Type
+	Kind      message.Kind
+	Producers []string
+	Consumers []string
+}
+
+// ProducerCount returns the number of handlers that produce the message.
+func (m snapshotMessage) ProducerCount() int {
+	return len(m.Producers)
+}
+
+// ConsumerCount returns the number of handlers that consume the message.
+func (m snapshotMessage) ConsumerCount() int {
+	return len(m.Consumers)
+}
+
+// loadSnapshotHandlers loads the handlers within an application as they were
+// at the given commit.
+func loadSnapshotHandlers(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	commit, appKey string,
+) ([]snapshotHandler, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT
+			h.key,
+			h.name,
+			h.handler_type,
+			h.type_package,
+			h.type_name,
+			h.is_pointer,
+			(
+				SELECT COUNT(*)
+				FROM dogmabrowser.snapshot_handler_message AS m
+				WHERE m.repository_id = h.repository_id
+				AND m.commit_hash = h.commit_hash
+				AND m.handler_key = h.key
+				AND m.is_consumed
+			) AS consumed_count,
+			(
+				SELECT COUNT(*)
+				FROM dogmabrowser.snapshot_handler_message AS m
+				WHERE m.repository_id = h.repository_id
+				AND m.commit_hash = h.commit_hash
+				AND m.handler_key = h.key
+				AND m.is_produced
+			) AS produced_count
+		FROM dogmabrowser.snapshot_handler AS h
+		WHERE h.repository_id = $1
+		AND h.commit_hash = $2
+		AND h.application_key = $3
+		ORDER BY h.name`,
+		repoID,
+		commit,
+		appKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var handlers []snapshotHandler
+
+	for rows.Next() {
+		var s snapshotHandler
+
+		if err := rows.Scan(
+			&s.Key,
+			&s.Name,
+			&s.Type,
+			&s.Impl.Package,
+			&s.Impl.Name,
+			&s.Impl.IsPointer,
+			&s.ConsumedMessageCount,
+			&s.ProducedMessageCount,
+		); err != nil {
+			return nil, err
+		}
+
+		handlers = append(handlers, s)
+	}
+
+	return handlers, rows.Err()
+}
+
+// loadSnapshotMessages loads the messages used by the handlers within an
+// application as they were at the given commit.
+func loadSnapshotMessages(
+	ctx context.Context,
+	db *sql.DB,
+	repoID int64,
+	commit, appKey string,
+) ([]snapshotMessage, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT
+			m.type_package,
+			m.type_name,
+			BOOL_OR(m.is_pointer),
+			MIN(m.kind),
+			COALESCE(STRING_AGG(h.name, E'\n' ORDER BY h.name) FILTER (WHERE m.is_produced), ''),
+			COALESCE(STRING_AGG(h.name, E'\n' ORDER BY h.name) FILTER (WHERE m.is_consumed), '')
+		FROM dogmabrowser.snapshot_handler_message AS m
+		INNER JOIN dogmabrowser.snapshot_handler AS h
+		ON h.repository_id = m.repository_id
+		AND h.commit_hash = m.commit_hash
+		AND h.key = m.handler_key
+		WHERE h.repository_id = $1
+		AND h.commit_hash = $2
+		AND h.application_key = $3
+		GROUP BY m.type_name, m.type_package
+		ORDER BY m.type_name, m.type_package`,
+		repoID,
+		commit,
+		appKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []snapshotMessage
+
+	for rows.Next() {
+		var (
+			s                          snapshotMessage
+			kind, producers, consumers string
+		)
+
+		if err := rows.Scan(
+			&s.Impl.Package,
+			&s.Impl.Name,
+			&s.Impl.IsPointer,
+			&kind,
+			&producers,
+			&consumers,
+		); err != nil {
+			return nil, err
+		}
+
+		if s.Kind, err = persistence.ParseKind(kind); err != nil {
+			return nil, err
+		}
+
+		if producers != "" {
+			s.Producers = strings.Split(producers, "\n")
+		}
+
+		if consumers != "" {
+			s.Consumers = strings.Split(consumers, "\n")
+		}
+
+		messages = append(messages, s)
+	}
+
+	return messages, rows.Err()
+}
@@ -0,0 +1,192 @@
+package applications
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/gin-gonic/gin"
+)
+
+// versionView is the template context for version.html.
+type versionView struct {
+	Key         string
+	Name        string
+	Impl        components.Type
+	Module      string
+	RepoID      int64
+	RepoName    string
+	Tag         string
+	IsRelease   bool
+	ReleaseName string
+	Commit      components.Commit
+	CommittedAt time.Time
+	PreviousTag string
+
+	Handlers        []snapshotHandler
+	RemovedHandlers []snapshotHandler
+	Messages        []snapshotMessage
+}
+
+// VersionHandler is an implementation of web.Handler that displays an
+// application as it was within a specific version of its repository.
+type VersionHandler struct {
+	DB *sql.DB
+}
+
+func (h *VersionHandler) Route() (string, string) {
+	return http.MethodGet, "/applications/:key/versions/*tag"
+}
+
+func (h *VersionHandler) Template() string {
+	return "applications/version.html"
+}
+
+func (h *VersionHandler) ActiveMenuItem() components.MenuItem {
+	return components.ApplicationsMenuItem
+}
+
+func (h *VersionHandler) View(ctx *gin.Context) (string, interface{}, error) {
+	var view versionView
+
+	appKey := ctx.Param("key")
+	tag := strings.TrimPrefix(ctx.Param("tag"), "/")
+
+	previousCommit, err := h.loadDetails(ctx, &view, appKey, tag)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return "", nil, nil
+		}
+
+		return "", nil, err
+	}
+
+	handlers, err := loadSnapshotHandlers(ctx, h.DB, view.RepoID, view.Commit.Hash, appKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if view.PreviousTag == "" {
+		view.Handlers = handlers
+	} else {
+		previous, err := loadSnapshotHandlers(ctx, h.DB, view.RepoID, previousCommit, appKey)
+		if err != nil {
+			return "", nil, err
+		}
+
+		current := map[string]bool{}
+		for _, s := range handlers {
+			current[s.Key] = true
+		}
+
+		existed := map[string]bool{}
+		for _, s := range previous {
+			existed[s.Key] = true
+
+			if !current[s.Key] {
+				view.RemovedHandlers = append(view.RemovedHandlers, s)
+			}
+		}
+
+		for _, s := range handlers {
+			s.IsNew = !existed[s.Key]
+			view.Handlers = append(view.Handlers, s)
+		}
+	}
+
+	view.Messages, err = loadSnapshotMessages(ctx, h.DB, view.RepoID, view.Commit.Hash, appKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return view.Name + " " + view.Tag, view, nil
+}
+
+// loadDetails loads information about the application at the version
+// identified by tag. It returns the hash of the commit of the previous version
+// that contains the application, if any.
+func (h *VersionHandler) loadDetails(
+	ctx context.Context,
+	view *versionView,
+	appKey, tag string,
+) (string, error) {
+	row := h.DB.QueryRowContext(
+		ctx,
+		`SELECT
+			a.key,
+			a.name,
+			a.type_package,
+			a.type_name,
+			a.is_pointer,
+			a.module_path,
+			r.id,
+			r.full_name,
+			r.html_url,
+			v.tag,
+			v.is_release,
+			v.release_name,
+			v.commit_hash,
+			s.committed_at,
+			COALESCE(p.tag, ''),
+			COALESCE(p.commit_hash, '')
+		FROM dogmabrowser.version AS v
+		INNER JOIN dogmabrowser.snapshot AS s
+		ON s.repository_id = v.repository_id
+		AND s.commit_hash = v.commit_hash
+		INNER JOIN dogmabrowser.snapshot_application AS a
+		ON a.repository_id = v.repository_id
+		AND a.commit_hash = v.commit_hash
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = v.repository_id
+		LEFT JOIN LATERAL (
+			SELECT
+				pv.tag,
+				pv.commit_hash
+			FROM dogmabrowser.version AS pv
+			INNER JOIN dogmabrowser.snapshot AS ps
+			ON ps.repository_id = pv.repository_id
+			AND ps.commit_hash = pv.commit_hash
+			INNER JOIN dogmabrowser.snapshot_application AS pa
+			ON pa.repository_id = pv.repository_id
+			AND pa.commit_hash = pv.commit_hash
+			WHERE pv.repository_id = v.repository_id
+			AND pa.key = a.key
+			AND ps.committed_at < s.committed_at
+			ORDER BY ps.committed_at DESC, pv.tag DESC
+			LIMIT 1
+		) AS p ON TRUE
+		WHERE a.key = $1
+		AND v.tag = $2
+		ORDER BY s.committed_at DESC
+		LIMIT 1`,
+		appKey,
+		tag,
+	)
+
+	var previousCommit string
+
+	err := row.Scan(
+		&view.Key,
+		&view.Name,
+		&view.Impl.Package,
+		&view.Impl.Name,
+		&view.Impl.IsPointer,
+		&view.Module,
+		&view.RepoID,
+		&view.RepoName,
+		&view.Commit.RepoURL,
+		&view.Tag,
+		&view.IsRelease,
+		&view.ReleaseName,
+		&view.Commit.Hash,
+		&view.CommittedAt,
+		&view.PreviousTag,
+		&previousCommit,
+	)
+
+	return previousCommit, err
+}
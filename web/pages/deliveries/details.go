@@ -0,0 +1,105 @@
+package deliveries
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/gin-gonic/gin"
+)
+
+type detailsView struct {
+	ID          string
+	Event       string
+	Action      string
+	RepoID      int64
+	RepoName    string
+	Outcome     string
+	Error       string
+	Receipts    int
+	Attempts    int
+	ReceivedAt  time.Time
+	ProcessedAt sql.NullTime
+	Payload     string
+}
+
+// DetailsHandler is an implementation of web.Handler that displays the details
+// of a single GitHub webhook delivery, including its payload.
+type DetailsHandler struct {
+	DB *sql.DB
+}
+
+func (h *DetailsHandler) Route() (string, string) {
+	return http.MethodGet, "/deliveries/:id"
+}
+
+func (h *DetailsHandler) Template() string {
+	return "deliveries/details.html"
+}
+
+func (h *DetailsHandler) ActiveMenuItem() components.MenuItem {
+	return components.DeliveriesMenuItem
+}
+
+func (h *DetailsHandler) View(ctx *gin.Context) (string, interface{}, error) {
+	row := h.DB.QueryRowContext(
+		ctx,
+		`SELECT
+			d.id,
+			d.event,
+			d.action,
+			COALESCE(d.repository_id, 0),
+			COALESCE(r.full_name, d.full_name),
+			d.outcome,
+			d.error,
+			d.receipts,
+			d.attempts,
+			d.received_at,
+			d.processed_at,
+			d.payload
+		FROM dogmabrowser.delivery AS d
+		LEFT JOIN dogmabrowser.repository AS r
+		ON r.id = d.repository_id
+		WHERE d.id = $1`,
+		ctx.Param("id"),
+	)
+
+	var (
+		view    detailsView
+		payload []byte
+	)
+
+	if err := row.Scan(
+		&view.ID,
+		&view.Event,
+		&view.Action,
+		&view.RepoID,
+		&view.RepoName,
+		&view.Outcome,
+		&view.Error,
+		&view.Receipts,
+		&view.Attempts,
+		&view.ReceivedAt,
+		&view.ProcessedAt,
+		&payload,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return "", nil, nil
+		}
+
+		return "", nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, payload, "", "  "); err == nil {
+		view.Payload = buf.String()
+	} else {
+		view.Payload = string(payload)
+	}
+
+	return "Delivery " + view.ID, view, nil
+}
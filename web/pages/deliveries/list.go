@@ -0,0 +1,115 @@
+package deliveries
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDeliveries is the maximum number of deliveries shown on the page.
+const maxDeliveries = 200
+
+type listView struct {
+	Outcome    string
+	Deliveries []deliverySummary
+}
+
+type deliverySummary struct {
+	ID         string
+	Event      string
+	Action     string
+	RepoID     int64
+	RepoName   string
+	Outcome    string
+	Error      string
+	Receipts   int
+	Attempts   int
+	ReceivedAt time.Time
+}
+
+// ListHandler is an implementation of web.Handler that displays the GitHub
+// webhook deliveries that have been received.
+type ListHandler struct {
+	DB *sql.DB
+}
+
+func (h *ListHandler) Route() (string, string) {
+	return http.MethodGet, "/deliveries"
+}
+
+func (h *ListHandler) Template() string {
+	return "deliveries/list.html"
+}
+
+func (h *ListHandler) ActiveMenuItem() components.MenuItem {
+	return components.DeliveriesMenuItem
+}
+
+func (h *ListHandler) View(ctx *gin.Context) (string, interface{}, error) {
+	view := listView{
+		Outcome: ctx.Query("outcome"),
+	}
+
+	if err := h.loadDeliveries(ctx, &view); err != nil {
+		return "", nil, err
+	}
+
+	return "Deliveries", view, nil
+}
+
+func (h *ListHandler) loadDeliveries(ctx context.Context, view *listView) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			d.id,
+			d.event,
+			d.action,
+			COALESCE(d.repository_id, 0),
+			COALESCE(r.full_name, d.full_name),
+			d.outcome,
+			d.error,
+			d.receipts,
+			d.attempts,
+			d.received_at
+		FROM dogmabrowser.delivery AS d
+		LEFT JOIN dogmabrowser.repository AS r
+		ON r.id = d.repository_id
+		WHERE $1 = ''
+		OR d.outcome = $1
+		ORDER BY d.received_at DESC
+		LIMIT $2`,
+		view.Outcome,
+		maxDeliveries,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s deliverySummary
+
+		if err := rows.Scan(
+			&s.ID,
+			&s.Event,
+			&s.Action,
+			&s.RepoID,
+			&s.RepoName,
+			&s.Outcome,
+			&s.Error,
+			&s.Receipts,
+			&s.Attempts,
+			&s.ReceivedAt,
+		); err != nil {
+			return err
+		}
+
+		view.Deliveries = append(view.Deliveries, s)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,201 @@
+package findings
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/dogmatiq/configkit/message"
+	"github.com/gin-gonic/gin"
+)
+
+// maxFindings is the maximum number of findings shown on the page.
+const maxFindings = 200
+
+// listView is the template context for list.html.
+type listView struct {
+	RepoID   int64
+	RepoName string
+	Findings []findingSummary
+}
+
+// findingSummary describes a change made within one repository that breaks an
+// application in another repository.
+type findingSummary struct {
+	Category   string
+	Type       components.Type
+	DetectedAt time.Time
+
+	// RepoID, RepoName and Commit identify the repository and commit that
+	// made the change.
+	RepoID   int64
+	RepoName string
+	Commit   components.Commit
+
+	// AffectedRepoID, AffectedRepoName and AffectedApplication identify the
+	// application that is broken by the change.
+	AffectedRepoID      int64
+	AffectedRepoName    string
+	AffectedApplication string
+
+	PreviousKind      message.Kind
+	CurrentKind       message.Kind
+	PreviousIsPointer bool
+	CurrentIsPointer  bool
+
+	// IdentityKey is the colliding key, for findings that describe an
+	// identity key collision.
+	IdentityKey string
+}
+
+// ListHandler is an implementation of web.Handler that displays breaking
+// changes to message contracts that affect other repositories, and identity
+// key collisions.
+type ListHandler struct {
+	DB *sql.DB
+}
+
+func (h *ListHandler) Route() (string, string) {
+	return http.MethodGet, "/findings"
+}
+
+func (h *ListHandler) Template() string {
+	return "findings/list.html"
+}
+
+func (h *ListHandler) ActiveMenuItem() components.MenuItem {
+	return components.FindingsMenuItem
+}
+
+func (h *ListHandler) View(ctx *gin.Context) (string, interface{}, error) {
+	var view listView
+
+	if id := ctx.Query("repository"); id != "" {
+		repoID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return "", nil, nil
+		}
+
+		row := h.DB.QueryRowContext(
+			ctx,
+			`SELECT full_name
+			FROM dogmabrowser.repository
+			WHERE id = $1`,
+			repoID,
+		)
+
+		if err := row.Scan(&view.RepoName); err != nil {
+			if err == sql.ErrNoRows {
+				ctx.AbortWithStatus(http.StatusNotFound)
+				return "", nil, nil
+			}
+
+			return "", nil, err
+		}
+
+		view.RepoID = repoID
+	}
+
+	if err := h.loadFindings(ctx, &view); err != nil {
+		return "", nil, err
+	}
+
+	return "Findings", view, nil
+}
+
+func (h *ListHandler) loadFindings(ctx context.Context, view *listView) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			f.category,
+			f.type_package,
+			f.type_name,
+			COALESCE(t.url, ''),
+			COALESCE(t.docs, ''),
+			f.detected_at,
+			r.id,
+			r.full_name,
+			r.html_url,
+			f.commit_hash,
+			x.id,
+			x.full_name,
+			f.affected_application,
+			COALESCE(f.previous_kind, ''),
+			COALESCE(f.current_kind, ''),
+			COALESCE(f.previous_is_pointer, FALSE),
+			COALESCE(f.current_is_pointer, FALSE),
+			f.identity_key
+		FROM dogmabrowser.finding AS f
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = f.repository_id
+		INNER JOIN dogmabrowser.repository AS x
+		ON x.id = f.affected_repository_id
+		LEFT JOIN dogmabrowser.type AS t
+		ON t.package = f.type_package
+		AND t.name = f.type_name
+		WHERE $1 = 0
+		OR f.repository_id = $1
+		OR f.affected_repository_id = $1
+		ORDER BY f.id DESC
+		LIMIT $2`,
+		view.RepoID,
+		maxFindings,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			s                         findingSummary
+			previousKind, currentKind string
+		)
+
+		if err := rows.Scan(
+			&s.Category,
+			&s.Type.Package,
+			&s.Type.Name,
+			&s.Type.URL,
+			&s.Type.Docs,
+			&s.DetectedAt,
+			&s.RepoID,
+			&s.RepoName,
+			&s.Commit.RepoURL,
+			&s.Commit.Hash,
+			&s.AffectedRepoID,
+			&s.AffectedRepoName,
+			&s.AffectedApplication,
+			&previousKind,
+			&currentKind,
+			&s.PreviousIsPointer,
+			&s.CurrentIsPointer,
+			&s.IdentityKey,
+		); err != nil {
+			return err
+		}
+
+		// Findings that do not describe a change of kind have an empty value,
+		// which is left as the zero kind.
+		if previousKind != "" {
+			if s.PreviousKind, err = persistence.ParseKind(previousKind); err != nil {
+				return err
+			}
+		}
+
+		if currentKind != "" {
+			if s.CurrentKind, err = persistence.ParseKind(currentKind); err != nil {
+				return err
+			}
+		}
+
+		view.Findings = append(view.Findings, s)
+	}
+
+	return rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"net/http"
+	"strings"
 
 	"github.com/dogmatiq/browser/web/components"
 	"github.com/dogmatiq/configkit"
@@ -18,6 +19,15 @@ type detailsView struct {
 	Impl    components.Type
 	AppKey  string
 	AppName string
+	Builds  []string
+
+	// FirstSeenTag is the tag of the earliest analyzed version that contains
+	// the handler, if any.
+	FirstSeenTag string
+
+	// Collisions is the handlers in other applications that declare the same
+	// identity key.
+	Collisions []collision
 
 	ConsumedMessages    []messageSummary
 	ConsumedMessageKind message.Kind
@@ -26,6 +36,16 @@ type detailsView struct {
 	TimeoutMessages     []components.Type
 }
 
+// collision is another declaration of a handler's identity key, for display
+// within a detailsView.
+type collision struct {
+	Name     string
+	Impl     components.Type
+	AppName  string
+	RepoID   int64
+	RepoName string
+}
+
 type messageSummary struct {
 	Impl         components.Type
 	HandlerCount int
@@ -72,6 +92,10 @@ func (h *DetailsHandler) View(ctx *gin.Context) (string, interface{}, error) {
 		view.ConsumedMessageKind = message.EventKind
 	}
 
+	if err := h.loadCollisions(ctx, &view, handlerKey); err != nil {
+		return "", nil, err
+	}
+
 	if err := h.loadMessages(ctx, &view, handlerKey); err != nil {
 		return "", nil, err
 	}
@@ -96,7 +120,25 @@ func (h *DetailsHandler) loadDetails(
 			COALESCE(t.url, ''),
 			COALESCE(t.docs, ''),
 			a.key,
-			a.name
+			a.name,
+			COALESCE((
+				SELECT STRING_AGG(x.build_config, E'\n' ORDER BY x.build_config)
+				FROM dogmabrowser.handler_build AS x
+				WHERE x.handler_key = h.key
+			), ''),
+			COALESCE((
+				SELECT v.tag
+				FROM dogmabrowser.snapshot_handler AS x
+				INNER JOIN dogmabrowser.version AS v
+				ON v.repository_id = x.repository_id
+				AND v.commit_hash = x.commit_hash
+				INNER JOIN dogmabrowser.snapshot AS s
+				ON s.repository_id = v.repository_id
+				AND s.commit_hash = v.commit_hash
+				WHERE x.key = h.key
+				ORDER BY s.committed_at, v.tag
+				LIMIT 1
+			), '')
 		FROM dogmabrowser.handler AS h
 		INNER JOIN dogmabrowser.type AS t
 		ON t.id = h.type_id
@@ -106,7 +148,9 @@ func (h *DetailsHandler) loadDetails(
 		handlerKey,
 	)
 
-	return row.Scan(
+	var builds string
+
+	if err := row.Scan(
 		&view.Key,
 		&view.Name,
 		&view.Type,
@@ -117,7 +161,17 @@ func (h *DetailsHandler) loadDetails(
 		&view.Impl.Docs,
 		&view.AppKey,
 		&view.AppName,
-	)
+		&builds,
+		&view.FirstSeenTag,
+	); err != nil {
+		return err
+	}
+
+	if builds != "" {
+		view.Builds = strings.Split(builds, "\n")
+	}
+
+	return nil
 }
 
 func (h *DetailsHandler) loadMessages(
@@ -160,7 +214,7 @@ func (h *DetailsHandler) loadMessages(
 		var s messageSummary
 
 		var (
-			kind                   message.Kind
+			kind                   string
 			isProduced, isConsumed bool
 		)
 
@@ -178,7 +232,7 @@ func (h *DetailsHandler) loadMessages(
 			return err
 		}
 
-		if kind == message.TimeoutKind {
+		if kind == message.TimeoutKind.String() {
 			view.TimeoutMessages = append(view.TimeoutMessages, s.Impl)
 		} else if isProduced {
 			view.ProducedMessages = append(view.ProducedMessages, s)
@@ -189,3 +243,66 @@ func (h *DetailsHandler) loadMessages(
 
 	return rows.Err()
 }
+
+func (h *DetailsHandler) loadCollisions(
+	ctx context.Context,
+	view *detailsView,
+	handlerKey string,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			d.name,
+			d.type_package,
+			d.type_name,
+			COALESCE(t.url, ''),
+			d.application_name,
+			r.id,
+			r.full_name
+		FROM dogmabrowser.identity_declaration AS d
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = d.repository_id
+		INNER JOIN dogmabrowser.application AS a
+		ON a.key = $2
+		LEFT JOIN dogmabrowser.type AS t
+		ON t.package = d.type_package
+		AND t.name = d.type_name
+		WHERE d.entity = 'handler'
+		AND d.key = $1
+		AND NOT (
+			d.repository_id = a.repository_id
+			AND d.application_key = $2
+			AND d.type_package = $3
+			AND d.type_name = $4
+		)
+		ORDER BY r.full_name, d.application_name, d.type_package, d.type_name`,
+		handlerKey,
+		view.AppKey,
+		view.Impl.Package,
+		view.Impl.Name,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c collision
+
+		if err := rows.Scan(
+			&c.Name,
+			&c.Impl.Package,
+			&c.Impl.Name,
+			&c.Impl.URL,
+			&c.AppName,
+			&c.RepoID,
+			&c.RepoName,
+		); err != nil {
+			return err
+		}
+
+		view.Collisions = append(view.Collisions, c)
+	}
+
+	return rows.Err()
+}
@@ -5,9 +5,13 @@ import (
 	"database/sql"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/browser/typeexpr"
 	"github.com/dogmatiq/browser/web/components"
 	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/configkit/message"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,12 +20,84 @@ type detailsView struct {
 	Kind               string
 	HasKindMismatch    bool
 	HasPointerMismatch bool
+	RepoID             int64
+	RepoName           string
+	Module             string
+
+	// Fields is the fields of the message type, if it is a struct, as defined
+	// by its canonical source.
+	Fields []field
+
+	// IsPersistedEvent is true if the message is an event that is recorded by
+	// an aggregate, and is therefore persisted in an event store.
+	IsPersistedEvent bool
+
+	// History is the changes to the message's fields, as defined by its
+	// canonical source, grouped by the commit that made them, most recent
+	// first.
+	History []shapeChangeSet
+
+	// BreakingChangeCount is the number of changes within History that could
+	// prevent previously persisted events from being read.
+	BreakingChangeCount int
+
+	// Alternatives is the other repositories that also define the message
+	// type, such as by vendoring or copying its package.
+	Alternatives []typeSource
 
 	Applications []applicationSummary
 	Producers    []handlerSummary
 	Consumers    []handlerSummary
 }
 
+// field is a field of a message struct type.
+type field struct {
+	Name       string
+	Type       components.Type
+	Tag        string
+	Docs       string
+	IsEmbedded bool
+
+	// IsMessage is true if the field's type is itself a message type.
+	IsMessage bool
+}
+
+// shapeChangeSet is a set of changes to the fields of a message that were made
+// by a single commit.
+type shapeChangeSet struct {
+	Commit      components.Commit
+	Previous    components.Commit
+	CommittedAt time.Time
+	Changes     []shapeChange
+}
+
+// shapeChange is a change to a single field of a message.
+type shapeChange struct {
+	Action string
+	Field  string
+	Before string
+	After  string
+}
+
+// IsBreaking returns true if the change could prevent previously persisted
+// messages from being read. Only the addition of a field is considered to be
+// safe.
+func (c shapeChange) IsBreaking() bool {
+	return c.Action != string(persistence.AddedAction)
+}
+
+// typeSource is a definition of a type within a specific repository.
+type typeSource struct {
+	RepoID   int64
+	RepoName string
+	Module   string
+	URL      string
+
+	// IsOwner is true if the definition is within the module that owns the
+	// type's package.
+	IsOwner bool
+}
+
 type applicationSummary struct {
 	Key  string
 	Name string
@@ -56,13 +132,14 @@ func (h *DetailsHandler) ActiveMenuItem() components.MenuItem {
 func (h *DetailsHandler) View(ctx *gin.Context) (string, interface{}, error) {
 	var view detailsView
 
-	name := strings.TrimPrefix(ctx.Param("name"), "/")
-	var pkg string
-
-	if n := strings.LastIndexByte(name, '.'); n != -1 {
-		pkg = name[:n]
-		name = name[n+1:]
-	}
+	// Types without a package, such as slices of messages, are linked with a
+	// leading dot.
+	pkg, name := typeexpr.Parse(
+		strings.TrimPrefix(
+			strings.TrimPrefix(ctx.Param("name"), "/"),
+			".",
+		),
+	).Split()
 
 	if err := h.loadDetails(ctx, &view, pkg, name); err != nil {
 		if err == sql.ErrNoRows {
@@ -73,6 +150,18 @@ func (h *DetailsHandler) View(ctx *gin.Context) (string, interface{}, error) {
 		return "", nil, err
 	}
 
+	if err := h.loadFields(ctx, &view, pkg, name); err != nil {
+		return "", nil, err
+	}
+
+	if err := h.loadHistory(ctx, &view, pkg, name); err != nil {
+		return "", nil, err
+	}
+
+	if err := h.loadAlternatives(ctx, &view, pkg, name); err != nil {
+		return "", nil, err
+	}
+
 	if err := h.loadApplications(ctx, &view, pkg, name); err != nil {
 		return "", nil, err
 	}
@@ -98,13 +187,18 @@ func (h *DetailsHandler) loadDetails(
 			COALESCE(t.docs, ''),
 			MODE() WITHIN GROUP (ORDER BY m.kind) AS kind,
 			COUNT(DISTINCT m.kind) > 1 AS has_kind_mismatch,
-			COUNT(DISTINCT m.is_pointer) > 1 AS has_pointer_mismatch
+			COUNT(DISTINCT m.is_pointer) > 1 AS has_pointer_mismatch,
+			COALESCE(r.id, 0),
+			COALESCE(r.full_name, ''),
+			t.module_path
 		FROM dogmabrowser.handler_message AS m
 		INNER JOIN dogmabrowser.type AS t
 		ON t.id = m.type_id
+		LEFT JOIN dogmabrowser.repository AS r
+		ON r.id = t.repository_id
 		WHERE t.package = $1
 		AND t.name = $2
-		GROUP BY t.id`,
+		GROUP BY t.id, r.id`,
 		pkg,
 		name,
 	)
@@ -117,9 +211,234 @@ func (h *DetailsHandler) loadDetails(
 		&view.Kind,
 		&view.HasKindMismatch,
 		&view.HasPointerMismatch,
+		&view.RepoID,
+		&view.RepoName,
+		&view.Module,
 	)
 }
 
+func (h *DetailsHandler) loadFields(
+	ctx context.Context,
+	view *detailsView,
+	pkg, name string,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			f.name,
+			f.type_package,
+			f.type_name,
+			f.is_pointer,
+			COALESCE(ft.url, ''),
+			COALESCE(ft.docs, ''),
+			EXISTS (
+				SELECT *
+				FROM dogmabrowser.handler_message AS m
+				WHERE m.type_id = ft.id
+			),
+			f.tag,
+			f.docs,
+			f.is_embedded
+		FROM dogmabrowser.type AS t
+		INNER JOIN dogmabrowser.type_field AS f
+		ON f.type_id = t.id
+		AND f.repository_id = t.repository_id
+		LEFT JOIN dogmabrowser.type AS ft
+		ON ft.package = f.type_package
+		AND ft.name = f.type_name
+		WHERE t.package = $1
+		AND t.name = $2
+		ORDER BY f.position`,
+		pkg,
+		name,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f field
+
+		if err := rows.Scan(
+			&f.Name,
+			&f.Type.Package,
+			&f.Type.Name,
+			&f.Type.IsPointer,
+			&f.Type.URL,
+			&f.Type.Docs,
+			&f.IsMessage,
+			&f.Tag,
+			&f.Docs,
+			&f.IsEmbedded,
+		); err != nil {
+			return err
+		}
+
+		view.Fields = append(view.Fields, f)
+	}
+
+	return rows.Err()
+}
+
+// maxHistoryItems is the maximum number of field changes shown in the history
+// of a message.
+const maxHistoryItems = 200
+
+func (h *DetailsHandler) loadHistory(
+	ctx context.Context,
+	view *detailsView,
+	pkg, name string,
+) error {
+	row := h.DB.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+			SELECT *
+			FROM dogmabrowser.type AS t
+			INNER JOIN dogmabrowser.handler_message AS m
+			ON m.type_id = t.id
+			INNER JOIN dogmabrowser.handler AS h
+			ON h.key = m.handler_key
+			WHERE t.package = $1
+			AND t.name = $2
+			AND m.kind = $3
+			AND m.is_produced
+			AND h.handler_type = $4
+		)`,
+		pkg,
+		name,
+		message.EventKind,
+		configkit.AggregateHandlerType,
+	)
+
+	if err := row.Scan(&view.IsPersistedEvent); err != nil {
+		return err
+	}
+
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			c.commit_hash,
+			c.previous_commit_hash,
+			s.committed_at,
+			r.html_url,
+			c.action,
+			c.name,
+			c.before_value,
+			c.after_value
+		FROM dogmabrowser.type AS t
+		INNER JOIN dogmabrowser.change AS c
+		ON c.repository_id = t.repository_id
+		AND c.entity = $3
+		AND c.message_type = t.package || '.' || t.name
+		INNER JOIN dogmabrowser.snapshot AS s
+		ON s.repository_id = c.repository_id
+		AND s.commit_hash = c.commit_hash
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = c.repository_id
+		WHERE t.package = $1
+		AND t.name = $2
+		ORDER BY s.committed_at DESC, c.commit_hash, c.id
+		LIMIT $4`,
+		pkg,
+		name,
+		persistence.MessageFieldChange,
+		maxHistoryItems,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			set     shapeChangeSet
+			repoURL string
+			c       shapeChange
+		)
+
+		if err := rows.Scan(
+			&set.Commit.Hash,
+			&set.Previous.Hash,
+			&set.CommittedAt,
+			&repoURL,
+			&c.Action,
+			&c.Field,
+			&c.Before,
+			&c.After,
+		); err != nil {
+			return err
+		}
+
+		if c.IsBreaking() {
+			view.BreakingChangeCount++
+		}
+
+		// Changes are ordered such that those from the same change set are
+		// adjacent.
+		if n := len(view.History); n > 0 && view.History[n-1].Commit.Hash == set.Commit.Hash {
+			view.History[n-1].Changes = append(view.History[n-1].Changes, c)
+			continue
+		}
+
+		set.Commit.RepoURL = repoURL
+		set.Previous.RepoURL = repoURL
+		set.Changes = []shapeChange{c}
+		view.History = append(view.History, set)
+	}
+
+	return rows.Err()
+}
+
+func (h *DetailsHandler) loadAlternatives(
+	ctx context.Context,
+	view *detailsView,
+	pkg, name string,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			r.id,
+			r.full_name,
+			s.module_path,
+			s.url,
+			s.is_owner
+		FROM dogmabrowser.type AS t
+		INNER JOIN dogmabrowser.type_source AS s
+		ON s.type_id = t.id
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = s.repository_id
+		WHERE t.package = $1
+		AND t.name = $2
+		AND s.repository_id IS DISTINCT FROM t.repository_id
+		ORDER BY s.is_owner DESC, r.id`,
+		pkg,
+		name,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s typeSource
+
+		if err := rows.Scan(
+			&s.RepoID,
+			&s.RepoName,
+			&s.Module,
+			&s.URL,
+			&s.IsOwner,
+		); err != nil {
+			return err
+		}
+
+		view.Alternatives = append(view.Alternatives, s)
+	}
+
+	return rows.Err()
+}
+
 func (h *DetailsHandler) loadApplications(
 	ctx context.Context,
 	view *detailsView,
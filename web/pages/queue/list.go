@@ -0,0 +1,164 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/gin-gonic/gin"
+)
+
+type listView struct {
+	Jobs        []jobSummary
+	DeadLetters []deadLetterSummary
+}
+
+type jobSummary struct {
+	RepoID     int64
+	RepoName   string
+	Operation  string
+	Trigger    string
+	Priority   int
+	Attempts   int
+	EnqueuedAt time.Time
+	NotBefore  time.Time
+	IsClaimed  bool
+	LastError  string
+}
+
+type deadLetterSummary struct {
+	ID         int64
+	RepoID     int64
+	RepoName   string
+	Operation  string
+	Attempts   int
+	Error      string
+	EnqueuedAt time.Time
+	FailedAt   time.Time
+}
+
+// ListHandler is an implementation of web.Handler that displays the jobs in
+// the analysis queue and the dead-letter list.
+type ListHandler struct {
+	DB *sql.DB
+}
+
+func (h *ListHandler) Route() (string, string) {
+	return http.MethodGet, "/queue"
+}
+
+func (h *ListHandler) Template() string {
+	return "queue/list.html"
+}
+
+func (h *ListHandler) ActiveMenuItem() components.MenuItem {
+	return components.QueueMenuItem
+}
+
+func (h *ListHandler) View(ctx *gin.Context) (string, interface{}, error) {
+	var view listView
+
+	if err := h.loadJobs(ctx, &view); err != nil {
+		return "", nil, err
+	}
+
+	if err := h.loadDeadLetters(ctx, &view); err != nil {
+		return "", nil, err
+	}
+
+	return "Queue", view, nil
+}
+
+func (h *ListHandler) loadJobs(ctx context.Context, view *listView) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			q.repository_id,
+			COALESCE(r.full_name, ''),
+			q.operation,
+			q.trigger,
+			q.priority,
+			q.attempts,
+			q.enqueued_at,
+			q.not_before,
+			COALESCE(q.claimed_until > NOW(), FALSE),
+			COALESCE(q.last_error, '')
+		FROM dogmabrowser.queue AS q
+		LEFT JOIN dogmabrowser.repository AS r
+		ON r.id = q.repository_id
+		ORDER BY q.priority DESC, q.id`,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s jobSummary
+
+		if err := rows.Scan(
+			&s.RepoID,
+			&s.RepoName,
+			&s.Operation,
+			&s.Trigger,
+			&s.Priority,
+			&s.Attempts,
+			&s.EnqueuedAt,
+			&s.NotBefore,
+			&s.IsClaimed,
+			&s.LastError,
+		); err != nil {
+			return err
+		}
+
+		view.Jobs = append(view.Jobs, s)
+	}
+
+	return rows.Err()
+}
+
+func (h *ListHandler) loadDeadLetters(ctx context.Context, view *listView) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			d.id,
+			d.repository_id,
+			COALESCE(r.full_name, ''),
+			d.operation,
+			d.attempts,
+			d.error,
+			d.enqueued_at,
+			d.failed_at
+		FROM dogmabrowser.dead_letter AS d
+		LEFT JOIN dogmabrowser.repository AS r
+		ON r.id = d.repository_id
+		ORDER BY d.failed_at DESC`,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s deadLetterSummary
+
+		if err := rows.Scan(
+			&s.ID,
+			&s.RepoID,
+			&s.RepoName,
+			&s.Operation,
+			&s.Attempts,
+			&s.Error,
+			&s.EnqueuedAt,
+			&s.FailedAt,
+		); err != nil {
+			return err
+		}
+
+		view.DeadLetters = append(view.DeadLetters, s)
+	}
+
+	return rows.Err()
+}
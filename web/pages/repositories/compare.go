@@ -0,0 +1,268 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/gin-gonic/gin"
+)
+
+// compareView is the template context for compare.html.
+type compareView struct {
+	ID       int64
+	FullName string
+	HTMLURL  string
+
+	// Snapshots is the list of analyzed commits that may be compared, newest
+	// first.
+	Snapshots []snapshotOption
+
+	Base components.Commit
+	Head components.Commit
+
+	Applications []persistence.Change
+	Handlers     []persistence.Change
+	MessageRoles []persistence.Change
+	Fields       []persistence.Change
+}
+
+// snapshotOption is an analyzed commit of the repository, for display within
+// the commit selectors of a compareView.
+type snapshotOption struct {
+	Hash        string
+	CommittedAt time.Time
+
+	// Labels is the names of the tags, environments and branch heads that
+	// refer to the commit.
+	Labels []string
+}
+
+// ShortHash returns the abbreviated commit hash.
+func (s snapshotOption) ShortHash() string {
+	return components.Commit{Hash: s.Hash}.ShortHash()
+}
+
+// CompareURL returns the URL of the GitHub page that compares the base and
+// head commits, or an empty string if the repository's URL is unknown.
+func (v compareView) CompareURL() string {
+	if v.HTMLURL == "" {
+		return ""
+	}
+
+	return v.HTMLURL + "/compare/" + v.Base.Hash + "..." + v.Head.Hash
+}
+
+// CompareHandler is an implementation of web.Handler that displays the changes
+// to a repository's Dogma topology between two analyzed commits.
+type CompareHandler struct {
+	DB *sql.DB
+}
+
+func (h *CompareHandler) Route() (string, string) {
+	return http.MethodGet, "/repositories/:id/compare"
+}
+
+func (h *CompareHandler) Template() string {
+	return "repositories/compare.html"
+}
+
+func (h *CompareHandler) ActiveMenuItem() components.MenuItem {
+	return components.RepositoriesMenuItem
+}
+
+func (h *CompareHandler) View(ctx *gin.Context) (string, interface{}, error) {
+	var view compareView
+
+	repoID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return "", nil, nil
+	}
+
+	headHash, err := h.loadDetails(ctx, &view, repoID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return "", nil, nil
+		}
+
+		return "", nil, err
+	}
+
+	if err := h.loadSnapshots(ctx, &view, repoID, headHash); err != nil {
+		return "", nil, err
+	}
+
+	view.Head = h.selectCommit(&view, ctx.Query("head"), headHash)
+
+	// By default the head is compared to the commit that precedes it.
+	defaultBase := ""
+	for i, s := range view.Snapshots {
+		if s.Hash == view.Head.Hash && i+1 < len(view.Snapshots) {
+			defaultBase = view.Snapshots[i+1].Hash
+			break
+		}
+	}
+
+	view.Base = h.selectCommit(&view, ctx.Query("base"), defaultBase)
+
+	if view.Base.Hash == "" || view.Head.Hash == "" {
+		return view.FullName + " Changes", view, nil
+	}
+
+	changes, err := persistence.CompareSnapshots(
+		ctx,
+		h.DB,
+		repoID,
+		view.Base.Hash,
+		view.Head.Hash,
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, c := range changes {
+		switch c.Entity {
+		case persistence.ApplicationChange:
+			view.Applications = append(view.Applications, c)
+		case persistence.HandlerChange:
+			view.Handlers = append(view.Handlers, c)
+		case persistence.MessageRoleChange:
+			view.MessageRoles = append(view.MessageRoles, c)
+		case persistence.MessageFieldChange:
+			view.Fields = append(view.Fields, c)
+		}
+	}
+
+	return view.FullName + " Changes", view, nil
+}
+
+// selectCommit returns the analyzed commit with the given hash, or the commit
+// with the default hash if hash is empty or does not refer to an analyzed
+// commit.
+func (h *CompareHandler) selectCommit(
+	view *compareView,
+	hash, def string,
+) components.Commit {
+	for _, s := range view.Snapshots {
+		if s.Hash == hash {
+			return components.Commit{RepoURL: view.HTMLURL, Hash: hash}
+		}
+	}
+
+	for _, s := range view.Snapshots {
+		if s.Hash == def {
+			return components.Commit{RepoURL: view.HTMLURL, Hash: def}
+		}
+	}
+
+	return components.Commit{}
+}
+
+// loadDetails loads information about the repository. It returns the hash of
+// the most recently analyzed commit of the default branch.
+func (h *CompareHandler) loadDetails(
+	ctx context.Context,
+	view *compareView,
+	repoID int64,
+) (string, error) {
+	row := h.DB.QueryRowContext(
+		ctx,
+		`SELECT
+			r.id,
+			r.full_name,
+			r.html_url,
+			r.commit_hash
+		FROM dogmabrowser.repository AS r
+		WHERE r.id = $1`,
+		repoID,
+	)
+
+	var commit string
+
+	if err := row.Scan(
+		&view.ID,
+		&view.FullName,
+		&view.HTMLURL,
+		&commit,
+	); err != nil {
+		return "", err
+	}
+
+	return commit, nil
+}
+
+// loadSnapshots loads the analyzed commits of the repository.
+func (h *CompareHandler) loadSnapshots(
+	ctx context.Context,
+	view *compareView,
+	repoID int64,
+	headHash string,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			s.commit_hash,
+			s.committed_at,
+			CONCAT_WS(
+				E'\n',
+				CASE WHEN s.commit_hash = $2 THEN 'default branch' END,
+				(
+					SELECT STRING_AGG(v.tag, E'\n' ORDER BY v.tag)
+					FROM dogmabrowser.version AS v
+					WHERE v.repository_id = s.repository_id
+					AND v.commit_hash = s.commit_hash
+				),
+				(
+					SELECT STRING_AGG(e.name, E'\n' ORDER BY e.position)
+					FROM dogmabrowser.environment AS e
+					WHERE e.repository_id = s.repository_id
+					AND e.commit_hash = s.commit_hash
+				),
+				(
+					SELECT STRING_AGG('#' || p.number, E'\n' ORDER BY p.number)
+					FROM dogmabrowser.pull_request AS p
+					WHERE p.repository_id = s.repository_id
+					AND p.head_commit_hash = s.commit_hash
+				)
+			)
+		FROM dogmabrowser.snapshot AS s
+		WHERE s.repository_id = $1
+		ORDER BY s.committed_at DESC, s.commit_hash`,
+		repoID,
+		headHash,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			s      snapshotOption
+			labels string
+		)
+
+		if err := rows.Scan(
+			&s.Hash,
+			&s.CommittedAt,
+			&labels,
+		); err != nil {
+			return err
+		}
+
+		if labels != "" {
+			s.Labels = strings.Split(labels, "\n")
+		}
+
+		view.Snapshots = append(view.Snapshots, s)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,731 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/gin-gonic/gin"
+)
+
+// detailsView is the template context for details.html.
+type detailsView struct {
+	ID          int64
+	FullName    string
+	HTMLURL     string
+	Team        string
+	Description string
+	IsPrivate   bool
+	Tags        []string
+	Commit      components.Commit
+	AnalyzedAt  sql.NullTime
+	SkipReason  string
+	TypeCount   int
+
+	// AnalyzerVersion is the version of the browser that most recently
+	// analyzed the repository. IsStale is true if the results are waiting to
+	// be analyzed again, such as after the browser has been upgraded.
+	AnalyzerVersion string
+	IsStale         bool
+
+	// Collisions is the identity keys declared by the repository that are
+	// also declared by another application or handler.
+	Collisions []keyCollision
+
+	// CausedFindingCount is the number of findings describing changes made by
+	// the repository that break other repositories, and AffectingFindingCount
+	// is the number of findings describing changes made by other repositories
+	// that break this one.
+	CausedFindingCount    int
+	AffectingFindingCount int
+
+	Applications []appSummary
+	Diagnostics  []diagnostic
+	ChangeSets   []changeSetSummary
+	Versions     []versionSummary
+	Environments []environmentSummary
+	Runs         []runSummary
+}
+
+// appSummary contains a summary of information about an application defined
+// within the repository, for display within a detailsView.
+type appSummary struct {
+	Key          string
+	Name         string
+	Module       string
+	Impl         components.Type
+	HandlerCount int
+	MessageCount int
+}
+
+// diagnostic is information about a problem that occurred when analyzing the
+// repository.
+type diagnostic struct {
+	Category string
+	Package  string
+	Position string
+	Message  string
+}
+
+// changeSetSummary contains a summary of the changes recorded when a new commit
+// of the repository's default branch was analyzed.
+type changeSetSummary struct {
+	Commit       components.Commit
+	Previous     components.Commit
+	RecordedAt   time.Time
+	AddedCount   int
+	RemovedCount int
+	ChangedCount int
+}
+
+// versionSummary contains a summary of a tagged version of the repository.
+type versionSummary struct {
+	Tag          string
+	ReleaseName  string
+	IsRelease    bool
+	Commit       components.Commit
+	CommittedAt  time.Time
+	SkipReason   string
+	HandlerCount int
+	Applications []versionApp
+}
+
+// versionApp is an application that was present within a version of the
+// repository.
+type versionApp struct {
+	Key  string
+	Name string
+}
+
+// environmentSummary contains a summary of a deployment environment of the
+// repository.
+type environmentSummary struct {
+	Name       string
+	Pattern    string
+	Ref        string
+	Commit     components.Commit
+	ResolvedAt time.Time
+}
+
+// runSummary contains a summary of a recent analysis run of the repository.
+type runSummary struct {
+	Commit    components.Commit
+	Trigger   string
+	StartedAt time.Time
+	Duration  time.Duration
+	Outcome   string
+	Error     string
+}
+
+// keyCollision describes an identity key that is declared by an application
+// or handler within the repository and also declared elsewhere, for display
+// within a detailsView.
+type keyCollision struct {
+	Entity string
+	Key    string
+	Name   string
+
+	// IsStored is true if the repository's declaration is the one that is
+	// browsable, as it was discovered first.
+	IsStored bool
+
+	// OtherName, OtherAppName, OtherRepoID and OtherRepoName identify the
+	// other declaration of the key.
+	OtherName     string
+	OtherAppName  string
+	OtherRepoID   int64
+	OtherRepoName string
+}
+
+// DetailsHandler is an implementation of web.Handler that displays detailed
+// information about a single repository.
+type DetailsHandler struct {
+	DB *sql.DB
+}
+
+func (h *DetailsHandler) Route() (string, string) {
+	return http.MethodGet, "/repositories/:id"
+}
+
+func (h *DetailsHandler) Template() string {
+	return "repositories/details.html"
+}
+
+func (h *DetailsHandler) ActiveMenuItem() components.MenuItem {
+	return components.RepositoriesMenuItem
+}
+
+func (h *DetailsHandler) View(ctx *gin.Context) (string, interface{}, error) {
+	var view detailsView
+
+	repoID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return "", nil, nil
+	}
+
+	if err := h.loadDetails(ctx, &view, repoID); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return "", nil, nil
+		}
+
+		return "", nil, err
+	}
+
+	if err := h.loadApplications(ctx, &view, repoID); err != nil {
+		return "", nil, err
+	}
+
+	if err := h.loadDiagnostics(ctx, &view, repoID); err != nil {
+		return "", nil, err
+	}
+
+	if err := h.loadCollisions(ctx, &view, repoID); err != nil {
+		return "", nil, err
+	}
+
+	if err := h.loadFindingCounts(ctx, &view, repoID); err != nil {
+		return "", nil, err
+	}
+
+	if err := h.loadChangeSets(ctx, &view, repoID); err != nil {
+		return "", nil, err
+	}
+
+	if err := h.loadVersions(ctx, &view, repoID); err != nil {
+		return "", nil, err
+	}
+
+	if err := h.loadEnvironments(ctx, &view, repoID); err != nil {
+		return "", nil, err
+	}
+
+	if err := h.loadRuns(ctx, &view, repoID); err != nil {
+		return "", nil, err
+	}
+
+	return view.FullName, view, nil
+}
+
+func (h *DetailsHandler) loadDetails(
+	ctx context.Context,
+	view *detailsView,
+	repoID int64,
+) error {
+	row := h.DB.QueryRowContext(
+		ctx,
+		`SELECT
+			r.id,
+			r.full_name,
+			r.html_url,
+			r.team,
+			r.description,
+			r.is_private,
+			(
+				SELECT COALESCE(STRING_AGG(x.tag, E'\n' ORDER BY x.tag), '')
+				FROM dogmabrowser.repository_tag AS x
+				WHERE x.repository_id = r.id
+			) AS tags,
+			r.commit_hash,
+			r.analyzed_at,
+			r.analyzer_version,
+			r.is_stale,
+			(
+				SELECT COALESCE(STRING_AGG(d.message, '; '), '')
+				FROM dogmabrowser.diagnostic AS d
+				WHERE d.repository_id = r.id
+				AND d.category = 'skipped'
+			) AS skip_reason,
+			(
+				SELECT COUNT(*)
+				FROM dogmabrowser.type AS t
+				WHERE t.repository_id = r.id
+			) AS type_count
+		FROM dogmabrowser.repository AS r
+		WHERE r.id = $1`,
+		repoID,
+	)
+
+	var tags string
+
+	if err := row.Scan(
+		&view.ID,
+		&view.FullName,
+		&view.HTMLURL,
+		&view.Team,
+		&view.Description,
+		&view.IsPrivate,
+		&tags,
+		&view.Commit.Hash,
+		&view.AnalyzedAt,
+		&view.AnalyzerVersion,
+		&view.IsStale,
+		&view.SkipReason,
+		&view.TypeCount,
+	); err != nil {
+		return err
+	}
+
+	view.Commit.RepoURL = view.HTMLURL
+
+	if tags != "" {
+		view.Tags = strings.Split(tags, "\n")
+	}
+
+	return nil
+}
+
+func (h *DetailsHandler) loadApplications(
+	ctx context.Context,
+	view *detailsView,
+	repoID int64,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			a.key,
+			a.name,
+			a.module_path,
+			t.package,
+			t.name,
+			a.is_pointer,
+			COALESCE(t.url, ''),
+			COALESCE(t.docs, ''),
+			(
+				SELECT COUNT(h.key)
+				FROM dogmabrowser.handler AS h
+				WHERE h.application_key = a.key
+			) AS handler_count,
+			(
+				SELECT COUNT(DISTINCT m.type_id)
+				FROM dogmabrowser.handler AS h
+				INNER JOIN dogmabrowser.handler_message AS m
+				ON m.handler_key = h.key
+				WHERE h.application_key = a.key
+			) AS message_count
+		FROM dogmabrowser.application AS a
+		INNER JOIN dogmabrowser.type AS t
+		ON t.id = a.type_id
+		WHERE a.repository_id = $1
+		ORDER BY a.module_path, a.name, a.key`,
+		repoID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s appSummary
+
+		if err := rows.Scan(
+			&s.Key,
+			&s.Name,
+			&s.Module,
+			&s.Impl.Package,
+			&s.Impl.Name,
+			&s.Impl.IsPointer,
+			&s.Impl.URL,
+			&s.Impl.Docs,
+			&s.HandlerCount,
+			&s.MessageCount,
+		); err != nil {
+			return err
+		}
+
+		view.Applications = append(view.Applications, s)
+	}
+
+	return rows.Err()
+}
+
+func (h *DetailsHandler) loadDiagnostics(
+	ctx context.Context,
+	view *detailsView,
+	repoID int64,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			d.category,
+			d.package,
+			d.position,
+			d.message
+		FROM dogmabrowser.diagnostic AS d
+		WHERE d.repository_id = $1
+		ORDER BY d.category, d.package, d.id`,
+		repoID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d diagnostic
+
+		if err := rows.Scan(
+			&d.Category,
+			&d.Package,
+			&d.Position,
+			&d.Message,
+		); err != nil {
+			return err
+		}
+
+		view.Diagnostics = append(view.Diagnostics, d)
+	}
+
+	return rows.Err()
+}
+
+func (h *DetailsHandler) loadCollisions(
+	ctx context.Context,
+	view *detailsView,
+	repoID int64,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			d.entity,
+			d.key,
+			d.name,
+			CASE
+				WHEN d.entity = 'application' THEN EXISTS (
+					SELECT *
+					FROM dogmabrowser.application AS a
+					INNER JOIN dogmabrowser.type AS t
+					ON t.id = a.type_id
+					WHERE a.key = d.key
+					AND a.repository_id = d.repository_id
+					AND t.package = d.type_package
+					AND t.name = d.type_name
+				)
+				ELSE EXISTS (
+					SELECT *
+					FROM dogmabrowser.handler AS h
+					INNER JOIN dogmabrowser.application AS a
+					ON a.key = h.application_key
+					INNER JOIN dogmabrowser.type AS t
+					ON t.id = h.type_id
+					WHERE h.key = d.key
+					AND h.application_key = d.application_key
+					AND a.repository_id = d.repository_id
+					AND t.package = d.type_package
+					AND t.name = d.type_name
+				)
+			END,
+			x.name,
+			x.application_name,
+			r.id,
+			r.full_name
+		FROM dogmabrowser.identity_declaration AS d
+		INNER JOIN dogmabrowser.identity_declaration AS x
+		ON x.entity = d.entity
+		AND x.key = d.key
+		AND (x.repository_id, x.application_key, x.type_package, x.type_name)
+			!= (d.repository_id, d.application_key, d.type_package, d.type_name)
+		INNER JOIN dogmabrowser.repository AS r
+		ON r.id = x.repository_id
+		WHERE d.repository_id = $1
+		ORDER BY d.entity, d.key, r.full_name, x.application_name`,
+		repoID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c keyCollision
+
+		if err := rows.Scan(
+			&c.Entity,
+			&c.Key,
+			&c.Name,
+			&c.IsStored,
+			&c.OtherName,
+			&c.OtherAppName,
+			&c.OtherRepoID,
+			&c.OtherRepoName,
+		); err != nil {
+			return err
+		}
+
+		view.Collisions = append(view.Collisions, c)
+	}
+
+	return rows.Err()
+}
+
+func (h *DetailsHandler) loadFindingCounts(
+	ctx context.Context,
+	view *detailsView,
+	repoID int64,
+) error {
+	row := h.DB.QueryRowContext(
+		ctx,
+		`SELECT
+			COUNT(*) FILTER (WHERE f.repository_id = $1),
+			COUNT(*) FILTER (WHERE f.affected_repository_id = $1)
+		FROM dogmabrowser.finding AS f
+		WHERE f.repository_id = $1
+		OR f.affected_repository_id = $1`,
+		repoID,
+	)
+
+	return row.Scan(
+		&view.CausedFindingCount,
+		&view.AffectingFindingCount,
+	)
+}
+
+func (h *DetailsHandler) loadChangeSets(
+	ctx context.Context,
+	view *detailsView,
+	repoID int64,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			c.commit_hash,
+			c.previous_commit_hash,
+			MAX(c.recorded_at),
+			COUNT(*) FILTER (WHERE c.action = 'added'),
+			COUNT(*) FILTER (WHERE c.action = 'removed'),
+			COUNT(*) FILTER (WHERE c.action IN ('changed', 'renamed'))
+		FROM dogmabrowser.change AS c
+		WHERE c.repository_id = $1
+		GROUP BY c.commit_hash, c.previous_commit_hash
+		ORDER BY MAX(c.id) DESC
+		LIMIT 10`,
+		repoID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s changeSetSummary
+
+		if err := rows.Scan(
+			&s.Commit.Hash,
+			&s.Previous.Hash,
+			&s.RecordedAt,
+			&s.AddedCount,
+			&s.RemovedCount,
+			&s.ChangedCount,
+		); err != nil {
+			return err
+		}
+
+		s.Commit.RepoURL = view.HTMLURL
+		s.Previous.RepoURL = view.HTMLURL
+
+		view.ChangeSets = append(view.ChangeSets, s)
+	}
+
+	return rows.Err()
+}
+
+func (h *DetailsHandler) loadVersions(
+	ctx context.Context,
+	view *detailsView,
+	repoID int64,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			v.tag,
+			v.release_name,
+			v.is_release,
+			v.commit_hash,
+			s.committed_at,
+			s.skip_reason,
+			(
+				SELECT COUNT(*)
+				FROM dogmabrowser.snapshot_handler AS x
+				WHERE x.repository_id = v.repository_id
+				AND x.commit_hash = v.commit_hash
+			) AS handler_count
+		FROM dogmabrowser.version AS v
+		INNER JOIN dogmabrowser.snapshot AS s
+		ON s.repository_id = v.repository_id
+		AND s.commit_hash = v.commit_hash
+		WHERE v.repository_id = $1
+		ORDER BY s.committed_at DESC, v.tag DESC`,
+		repoID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	index := map[string]int{}
+
+	for rows.Next() {
+		var s versionSummary
+
+		if err := rows.Scan(
+			&s.Tag,
+			&s.ReleaseName,
+			&s.IsRelease,
+			&s.Commit.Hash,
+			&s.CommittedAt,
+			&s.SkipReason,
+			&s.HandlerCount,
+		); err != nil {
+			return err
+		}
+
+		s.Commit.RepoURL = view.HTMLURL
+
+		index[s.Tag] = len(view.Versions)
+		view.Versions = append(view.Versions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = h.DB.QueryContext(
+		ctx,
+		`SELECT
+			v.tag,
+			a.key,
+			a.name
+		FROM dogmabrowser.version AS v
+		INNER JOIN dogmabrowser.snapshot_application AS a
+		ON a.repository_id = v.repository_id
+		AND a.commit_hash = v.commit_hash
+		WHERE v.repository_id = $1
+		ORDER BY a.name, a.key`,
+		repoID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			tag string
+			a   versionApp
+		)
+
+		if err := rows.Scan(
+			&tag,
+			&a.Key,
+			&a.Name,
+		); err != nil {
+			return err
+		}
+
+		if i, ok := index[tag]; ok {
+			view.Versions[i].Applications = append(view.Versions[i].Applications, a)
+		}
+	}
+
+	return rows.Err()
+}
+
+func (h *DetailsHandler) loadEnvironments(
+	ctx context.Context,
+	view *detailsView,
+	repoID int64,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			e.name,
+			e.pattern,
+			e.ref,
+			COALESCE(e.commit_hash, ''),
+			e.resolved_at
+		FROM dogmabrowser.environment AS e
+		WHERE e.repository_id = $1
+		ORDER BY e.position`,
+		repoID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s environmentSummary
+
+		if err := rows.Scan(
+			&s.Name,
+			&s.Pattern,
+			&s.Ref,
+			&s.Commit.Hash,
+			&s.ResolvedAt,
+		); err != nil {
+			return err
+		}
+
+		s.Commit.RepoURL = view.HTMLURL
+
+		view.Environments = append(view.Environments, s)
+	}
+
+	return rows.Err()
+}
+
+func (h *DetailsHandler) loadRuns(
+	ctx context.Context,
+	view *detailsView,
+	repoID int64,
+) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			x.commit_hash,
+			x.trigger,
+			x.started_at,
+			(EXTRACT(EPOCH FROM x.ended_at - x.started_at) * 1000)::BIGINT,
+			x.outcome,
+			x.error
+		FROM dogmabrowser.analysis_run AS x
+		WHERE x.repository_id = $1
+		ORDER BY x.id DESC
+		LIMIT 10`,
+		repoID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			s        runSummary
+			duration int64
+		)
+
+		if err := rows.Scan(
+			&s.Commit.Hash,
+			&s.Trigger,
+			&s.StartedAt,
+			&duration,
+			&s.Outcome,
+			&s.Error,
+		); err != nil {
+			return err
+		}
+
+		s.Commit.RepoURL = view.HTMLURL
+		s.Duration = time.Duration(duration) * time.Millisecond
+
+		view.Runs = append(view.Runs, s)
+	}
+
+	return rows.Err()
+}
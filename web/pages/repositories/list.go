@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/gin-gonic/gin"
+)
+
+// listView is the template context for list.html.
+type listView struct {
+	Team         string
+	Tag          string
+	Repositories []repoSummary
+}
+
+// repoSummary contains a summary of information about a repository, for
+// display within a listView.
+type repoSummary struct {
+	ID              int64
+	FullName        string
+	HTMLURL         string
+	Team            string
+	Tags            []string
+	Commit          components.Commit
+	AnalyzedAt      sql.NullTime
+	SkipReason      string
+	DiagnosticCount int
+	AppCount        int
+	HandlerCount    int
+	TypeCount       int
+}
+
+// ListHandler is an implementation of web.Handler that displays a list of the
+// repositories that have been analyzed.
+type ListHandler struct {
+	DB *sql.DB
+}
+
+func (h *ListHandler) Route() (string, string) {
+	return http.MethodGet, "/repositories"
+}
+
+func (h *ListHandler) Template() string {
+	return "repositories/list.html"
+}
+
+func (h *ListHandler) ActiveMenuItem() components.MenuItem {
+	return components.RepositoriesMenuItem
+}
+
+func (h *ListHandler) View(ctx *gin.Context) (string, interface{}, error) {
+	view := listView{
+		Team: ctx.Query("team"),
+		Tag:  ctx.Query("tag"),
+	}
+
+	if err := h.loadRepositories(ctx, &view); err != nil {
+		return "", nil, err
+	}
+
+	return "Repositories", view, nil
+}
+
+func (h *ListHandler) loadRepositories(ctx context.Context, view *listView) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			r.id,
+			r.full_name,
+			r.html_url,
+			r.team,
+			(
+				SELECT COALESCE(STRING_AGG(x.tag, E'\n' ORDER BY x.tag), '')
+				FROM dogmabrowser.repository_tag AS x
+				WHERE x.repository_id = r.id
+			) AS tags,
+			r.commit_hash,
+			r.analyzed_at,
+			(
+				SELECT COALESCE(STRING_AGG(d.message, '; '), '')
+				FROM dogmabrowser.diagnostic AS d
+				WHERE d.repository_id = r.id
+				AND d.category = 'skipped'
+			) AS skip_reason,
+			(
+				SELECT COUNT(*)
+				FROM dogmabrowser.diagnostic AS d
+				WHERE d.repository_id = r.id
+				AND d.category != 'skipped'
+			) AS diagnostic_count,
+			(
+				SELECT COUNT(*)
+				FROM dogmabrowser.application AS a
+				WHERE a.repository_id = r.id
+			) AS app_count,
+			(
+				SELECT COUNT(*)
+				FROM dogmabrowser.handler AS h
+				INNER JOIN dogmabrowser.application AS a
+				ON a.key = h.application_key
+				WHERE a.repository_id = r.id
+			) AS handler_count,
+			(
+				SELECT COUNT(*)
+				FROM dogmabrowser.type AS t
+				WHERE t.repository_id = r.id
+			) AS type_count
+		FROM dogmabrowser.repository AS r
+		WHERE ($1 = '' OR r.team = $1)
+		AND ($2 = '' OR EXISTS (
+			SELECT *
+			FROM dogmabrowser.repository_tag AS x
+			WHERE x.repository_id = r.id
+			AND x.tag = $2
+		))
+		ORDER BY r.full_name`,
+		view.Team,
+		view.Tag,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			s    repoSummary
+			tags string
+		)
+
+		if err := rows.Scan(
+			&s.ID,
+			&s.FullName,
+			&s.HTMLURL,
+			&s.Team,
+			&tags,
+			&s.Commit.Hash,
+			&s.AnalyzedAt,
+			&s.SkipReason,
+			&s.DiagnosticCount,
+			&s.AppCount,
+			&s.HandlerCount,
+			&s.TypeCount,
+		); err != nil {
+			return err
+		}
+
+		s.Commit.RepoURL = s.HTMLURL
+
+		if tags != "" {
+			s.Tags = strings.Split(tags, "\n")
+		}
+
+		view.Repositories = append(view.Repositories, s)
+	}
+
+	return rows.Err()
+}
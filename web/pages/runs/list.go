@@ -0,0 +1,220 @@
+package runs
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dogmatiq/browser/web/components"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRuns is the maximum number of runs shown on the page.
+const maxRuns = 200
+
+// slowestPeriod is the period over which run timings are aggregated to find
+// the slowest repositories.
+const slowestPeriod = 7 * 24 * time.Hour
+
+type listView struct {
+	RepoID   int64
+	RepoName string
+	Runs     []runSummary
+	Slowest  []repoTimings
+}
+
+type runSummary struct {
+	ID        int64
+	RepoID    int64
+	RepoName  string
+	Commit    components.Commit
+	Trigger   string
+	StartedAt time.Time
+	Duration  time.Duration
+	Download  time.Duration
+	Load      time.Duration
+	Sync      time.Duration
+	Outcome   string
+	Error     string
+}
+
+type repoTimings struct {
+	RepoID      int64
+	RepoName    string
+	RunCount    int
+	AvgDuration time.Duration
+	MaxDuration time.Duration
+	AvgDownload time.Duration
+	AvgLoad     time.Duration
+	AvgSync     time.Duration
+}
+
+// ListHandler is an implementation of web.Handler that displays the history
+// of analysis runs.
+type ListHandler struct {
+	DB *sql.DB
+}
+
+func (h *ListHandler) Route() (string, string) {
+	return http.MethodGet, "/runs"
+}
+
+func (h *ListHandler) Template() string {
+	return "runs/list.html"
+}
+
+func (h *ListHandler) ActiveMenuItem() components.MenuItem {
+	return components.RunsMenuItem
+}
+
+func (h *ListHandler) View(ctx *gin.Context) (string, interface{}, error) {
+	var view listView
+
+	if id := ctx.Query("repository"); id != "" {
+		repoID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return "", nil, nil
+		}
+
+		view.RepoID = repoID
+	}
+
+	if err := h.loadRuns(ctx, &view); err != nil {
+		return "", nil, err
+	}
+
+	if view.RepoID != 0 {
+		if len(view.Runs) != 0 {
+			view.RepoName = view.Runs[0].RepoName
+		}
+	} else if err := h.loadSlowest(ctx, &view); err != nil {
+		return "", nil, err
+	}
+
+	return "Analysis Runs", view, nil
+}
+
+func (h *ListHandler) loadRuns(ctx context.Context, view *listView) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			x.id,
+			x.repository_id,
+			x.full_name,
+			COALESCE(r.html_url, ''),
+			x.commit_hash,
+			x.trigger,
+			x.started_at,
+			(EXTRACT(EPOCH FROM x.ended_at - x.started_at) * 1000)::BIGINT,
+			x.download_ms,
+			x.load_ms,
+			x.sync_ms,
+			x.outcome,
+			x.error
+		FROM dogmabrowser.analysis_run AS x
+		LEFT JOIN dogmabrowser.repository AS r
+		ON r.id = x.repository_id
+		WHERE $1 = 0 OR x.repository_id = $1
+		ORDER BY x.id DESC
+		LIMIT $2`,
+		view.RepoID,
+		maxRuns,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			s                           runSummary
+			total, download, load, sync int64
+		)
+
+		if err := rows.Scan(
+			&s.ID,
+			&s.RepoID,
+			&s.RepoName,
+			&s.Commit.RepoURL,
+			&s.Commit.Hash,
+			&s.Trigger,
+			&s.StartedAt,
+			&total,
+			&download,
+			&load,
+			&sync,
+			&s.Outcome,
+			&s.Error,
+		); err != nil {
+			return err
+		}
+
+		s.Duration = time.Duration(total) * time.Millisecond
+		s.Download = time.Duration(download) * time.Millisecond
+		s.Load = time.Duration(load) * time.Millisecond
+		s.Sync = time.Duration(sync) * time.Millisecond
+
+		view.Runs = append(view.Runs, s)
+	}
+
+	return rows.Err()
+}
+
+func (h *ListHandler) loadSlowest(ctx context.Context, view *listView) error {
+	rows, err := h.DB.QueryContext(
+		ctx,
+		`SELECT
+			x.repository_id,
+			MAX(x.full_name),
+			COUNT(*),
+			AVG(EXTRACT(EPOCH FROM x.ended_at - x.started_at) * 1000)::BIGINT,
+			MAX(EXTRACT(EPOCH FROM x.ended_at - x.started_at) * 1000)::BIGINT,
+			AVG(x.download_ms)::BIGINT,
+			AVG(x.load_ms)::BIGINT,
+			AVG(x.sync_ms)::BIGINT
+		FROM dogmabrowser.analysis_run AS x
+		WHERE x.outcome = 'analyzed'
+		AND x.started_at > NOW() - $1 * INTERVAL '1 millisecond'
+		GROUP BY x.repository_id
+		ORDER BY 4 DESC
+		LIMIT 10`,
+		slowestPeriod.Milliseconds(),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			s                                        repoTimings
+			avgTotal, maxTotal, download, load, sync int64
+		)
+
+		if err := rows.Scan(
+			&s.RepoID,
+			&s.RepoName,
+			&s.RunCount,
+			&avgTotal,
+			&maxTotal,
+			&download,
+			&load,
+			&sync,
+		); err != nil {
+			return err
+		}
+
+		s.AvgDuration = time.Duration(avgTotal) * time.Millisecond
+		s.MaxDuration = time.Duration(maxTotal) * time.Millisecond
+		s.AvgDownload = time.Duration(download) * time.Millisecond
+		s.AvgLoad = time.Duration(load) * time.Millisecond
+		s.AvgSync = time.Duration(sync) * time.Millisecond
+
+		view.Slowest = append(view.Slowest, s)
+	}
+
+	return rows.Err()
+}
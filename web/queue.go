@@ -0,0 +1,34 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dogmatiq/browser/analyzer"
+	"github.com/gin-gonic/gin"
+)
+
+func retryDeadLetter(version string, o *analyzer.Orchestrator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+		if err != nil {
+			renderError(ctx, version, http.StatusNotFound)
+			return
+		}
+
+		ok, err := o.RetryDeadLetter(ctx, id)
+		if err != nil {
+			fmt.Println("unable to retry dead-lettered job:", err) // TODO
+			renderError(ctx, version, http.StatusInternalServerError)
+			return
+		}
+
+		if !ok {
+			renderError(ctx, version, http.StatusNotFound)
+			return
+		}
+
+		ctx.Redirect(http.StatusSeeOther, "/queue")
+	}
+}
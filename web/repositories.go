@@ -0,0 +1,29 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dogmatiq/browser/analyzer"
+	"github.com/dogmatiq/browser/persistence"
+	"github.com/gin-gonic/gin"
+)
+
+func analyzeRepository(version string, o *analyzer.Orchestrator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+		if err != nil {
+			renderError(ctx, version, http.StatusNotFound)
+			return
+		}
+
+		if err := o.EnqueueAnalyis(ctx, id, persistence.ManualTrigger); err != nil {
+			fmt.Println("unable to enqueue analysis:", err) // TODO
+			renderError(ctx, version, http.StatusInternalServerError)
+			return
+		}
+
+		ctx.Redirect(http.StatusSeeOther, "/queue")
+	}
+}
@@ -11,8 +11,13 @@ import (
 	"github.com/dogmatiq/browser/githubx"
 	"github.com/dogmatiq/browser/web/components"
 	"github.com/dogmatiq/browser/web/pages/applications"
+	"github.com/dogmatiq/browser/web/pages/deliveries"
+	"github.com/dogmatiq/browser/web/pages/findings"
 	"github.com/dogmatiq/browser/web/pages/handlers"
 	"github.com/dogmatiq/browser/web/pages/messages"
+	"github.com/dogmatiq/browser/web/pages/queue"
+	"github.com/dogmatiq/browser/web/pages/repositories"
+	"github.com/dogmatiq/browser/web/pages/runs"
 	"github.com/gin-gonic/gin"
 	"github.com/google/go-github/v38/github"
 )
@@ -85,10 +90,20 @@ func NewRouter(
 		&applications.ListHandler{DB: db},
 		&applications.DetailsHandler{DB: db},
 		&applications.RelationshipHandler{DB: db},
+		&applications.VersionHandler{DB: db},
+		&applications.EnvironmentsHandler{DB: db},
+		&deliveries.ListHandler{DB: db},
+		&deliveries.DetailsHandler{DB: db},
+		&findings.ListHandler{DB: db},
 		&handlers.ListHandler{DB: db},
 		&handlers.DetailsHandler{DB: db},
 		&messages.ListHandler{DB: db},
 		&messages.DetailsHandler{DB: db},
+		&queue.ListHandler{DB: db},
+		&repositories.ListHandler{DB: db},
+		&repositories.DetailsHandler{DB: db},
+		&repositories.CompareHandler{DB: db},
+		&runs.ListHandler{DB: db},
 	}
 
 	for _, h := range handlers {
@@ -102,6 +117,36 @@ func NewRouter(
 		)
 	}
 
+	engine.POST(
+		"/queue/dead-letters/:id/retry",
+		auth,
+		retryDeadLetter(version, o),
+	)
+
+	engine.POST(
+		"/deliveries/:id/replay",
+		auth,
+		replayDelivery(version, o),
+	)
+
+	engine.GET(
+		"/types/*name",
+		auth,
+		viewTypeSource(version, db),
+	)
+
+	engine.POST(
+		"/repositories/:id/analyze",
+		auth,
+		analyzeRepository(version, o),
+	)
+
+	engine.GET(
+		"/runs.json",
+		auth,
+		listRuns(version, db),
+	)
+
 	engine.NoRoute(
 		func(ctx *gin.Context) {
 			renderError(ctx, version, http.StatusNotFound)
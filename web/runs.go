@@ -0,0 +1,122 @@
+package web
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRunItems is the maximum number of runs that may be requested from
+// /runs.json.
+const maxRunItems = 1000
+
+type runItem struct {
+	ID           int64     `json:"id"`
+	RepositoryID int64     `json:"repository_id"`
+	FullName     string    `json:"full_name"`
+	CommitHash   string    `json:"commit_hash,omitempty"`
+	Trigger      string    `json:"trigger"`
+	StartedAt    time.Time `json:"started_at"`
+	EndedAt      time.Time `json:"ended_at"`
+	DownloadMS   int64     `json:"download_ms"`
+	LoadMS       int64     `json:"load_ms"`
+	SyncMS       int64     `json:"sync_ms"`
+	Outcome      string    `json:"outcome"`
+	Error        string    `json:"error,omitempty"`
+}
+
+func listRuns(version string, db *sql.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var repoID int64
+		if v := ctx.Query("repository"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				renderError(ctx, version, http.StatusBadRequest)
+				return
+			}
+			repoID = id
+		}
+
+		limit := 100
+		if v := ctx.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				renderError(ctx, version, http.StatusBadRequest)
+				return
+			}
+			if n < maxRunItems {
+				limit = n
+			} else {
+				limit = maxRunItems
+			}
+		}
+
+		rows, err := db.QueryContext(
+			ctx,
+			`SELECT
+				x.id,
+				x.repository_id,
+				x.full_name,
+				x.commit_hash,
+				x.trigger,
+				x.started_at,
+				x.ended_at,
+				x.download_ms,
+				x.load_ms,
+				x.sync_ms,
+				x.outcome,
+				x.error
+			FROM dogmabrowser.analysis_run AS x
+			WHERE $1 = 0 OR x.repository_id = $1
+			ORDER BY x.id DESC
+			LIMIT $2`,
+			repoID,
+			limit,
+		)
+		if err != nil {
+			fmt.Println("unable to query analysis runs:", err) // TODO
+			renderError(ctx, version, http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		items := []runItem{}
+
+		for rows.Next() {
+			var item runItem
+
+			if err := rows.Scan(
+				&item.ID,
+				&item.RepositoryID,
+				&item.FullName,
+				&item.CommitHash,
+				&item.Trigger,
+				&item.StartedAt,
+				&item.EndedAt,
+				&item.DownloadMS,
+				&item.LoadMS,
+				&item.SyncMS,
+				&item.Outcome,
+				&item.Error,
+			); err != nil {
+				fmt.Println("unable to scan analysis run:", err) // TODO
+				renderError(ctx, version, http.StatusInternalServerError)
+				return
+			}
+
+			items = append(items, item)
+		}
+
+		if err := rows.Err(); err != nil {
+			fmt.Println("unable to iterate analysis runs:", err) // TODO
+			renderError(ctx, version, http.StatusInternalServerError)
+			return
+		}
+
+		ctx.PureJSON(http.StatusOK, items)
+	}
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dogmatiq/browser/typeexpr"
+	"github.com/gin-gonic/gin"
+)
+
+// viewTypeSource redirects to the source code of the type named in the URL,
+// which may be an instantiation of a generic type.
+//
+// It is used to link to the types that appear within a type expression, such
+// as the type arguments of a generic message type.
+func viewTypeSource(version string, db *sql.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		e := typeexpr.Parse(
+			strings.TrimPrefix(ctx.Param("name"), "/"),
+		)
+		pkg, name := e.Split()
+
+		row := db.QueryRowContext(
+			ctx,
+			`SELECT COALESCE(t.url, o.url, '')
+			FROM dogmabrowser.type AS t
+			LEFT JOIN dogmabrowser.type AS o
+			ON o.id = t.origin_id
+			WHERE t.package = $1
+			AND t.name = $2`,
+			pkg,
+			name,
+		)
+
+		var u string
+		if err := row.Scan(&u); err != nil {
+			if err == sql.ErrNoRows {
+				renderError(ctx, version, http.StatusNotFound)
+				return
+			}
+
+			fmt.Println("unable to load type:", err) // TODO
+			renderError(ctx, version, http.StatusInternalServerError)
+			return
+		}
+
+		if u == "" {
+			renderError(ctx, version, http.StatusNotFound)
+			return
+		}
+
+		ctx.Redirect(http.StatusFound, u)
+	}
+}